@@ -1,9 +1,17 @@
 package nimsforestviewer
 
 import (
+	"image"
+
 	sprites "github.com/nimsforest/nimsforestsprites"
 )
 
+// Grid geometry of frames produced by the sprite renderer.
+const (
+	spriteGridOrigin = 100 // Pixel offset of grid cell (0, 0) from the frame corner
+	spriteTileSize   = 64  // Edge length of a grid cell in pixels at scale 1.0
+)
+
 // SpritesStateAdapter adapts ViewState to sprites.State interface.
 type SpritesStateAdapter struct {
 	viewState *ViewState
@@ -15,11 +23,14 @@ func NewSpritesStateAdapter(state *ViewState) *SpritesStateAdapter {
 }
 
 // Lands implements sprites.State.
+// sprites.Land has no capacity or load fields, so Occupancy, RAMTotal and
+// RAMAllocated don't reach the renderer; lands are shaded by type only.
 func (a *SpritesStateAdapter) Lands() []sprites.Land {
 	if a.viewState == nil {
 		return nil
 	}
 
+	positions := layoutGrid(a.viewState.Lands)
 	result := make([]sprites.Land, len(a.viewState.Lands))
 	for i, land := range a.viewState.Lands {
 		landType := "normal"
@@ -29,8 +40,8 @@ func (a *SpritesStateAdapter) Lands() []sprites.Land {
 		result[i] = sprites.Land{
 			ID:   land.ID,
 			Name: land.Hostname,
-			X:    float64(land.GridX),
-			Y:    float64(land.GridY),
+			X:    float64(positions[i].X),
+			Y:    float64(positions[i].Y),
 			Type: landType,
 		}
 	}
@@ -38,49 +49,99 @@ func (a *SpritesStateAdapter) Lands() []sprites.Land {
 }
 
 // Processes implements sprites.State.
+// sprites.Process has no size, weight or name field, so RAMAllocated and Name
+// can't be passed to the renderer; every process of a type is drawn the same.
+// Subjects aren't drawn either: the renderer has no notion of links between
+// processes, so the dataflow graph is only available from the JSON "edges".
+// Treehouses follow the tree named by their ParentID, so they're drawn on top
+// of their parent; treehouses without a parent on the land come after all trees.
 func (a *SpritesStateAdapter) Processes() []sprites.Process {
 	if a.viewState == nil {
 		return nil
 	}
 
+	positions := layoutGrid(a.viewState.Lands)
 	var result []sprites.Process
-	for _, land := range a.viewState.Lands {
-		// Add trees
-		for _, proc := range land.Trees {
-			result = append(result, sprites.Process{
-				ID:       proc.ID,
-				LandID:   land.ID,
-				Type:     "tree",
-				Progress: proc.Progress,
-				X:        float64(land.GridX),
-				Y:        float64(land.GridY),
-			})
-		}
-		// Add treehouses
-		for _, proc := range land.Treehouses {
-			result = append(result, sprites.Process{
-				ID:       proc.ID,
-				LandID:   land.ID,
-				Type:     "treehouse",
-				Progress: proc.Progress,
-				X:        float64(land.GridX),
-				Y:        float64(land.GridY),
-			})
+	for i, land := range a.viewState.Lands {
+		pos := positions[i]
+		add := func(procs []ProcessView, procType string) {
+			for _, proc := range procs {
+				sp := sprites.Process{
+					ID:       proc.ID,
+					LandID:   land.ID,
+					Type:     procType,
+					Progress: clamp01(proc.Progress), // The renderer draws garbage bars outside 0-1
+					X:        float64(pos.X),
+					Y:        float64(pos.Y),
+				}
+				result = append(result, sp)
+			}
 		}
-		// Add nims
-		for _, proc := range land.Nims {
-			result = append(result, sprites.Process{
-				ID:       proc.ID,
-				LandID:   land.ID,
-				Type:     "nim",
-				Progress: proc.Progress,
-				X:        float64(land.GridX),
-				Y:        float64(land.GridY),
-			})
+		children, orphans := groupByParent(land.Trees, land.Treehouses)
+		for _, tree := range land.Trees {
+			add([]ProcessView{tree}, "tree")
+			add(children[tree.ID], "treehouse")
 		}
+		add(orphans, "treehouse")
+		add(land.Nims, "nim")
 	}
 	return result
 }
 
+// failedCells returns the pixel rects, in a frame rendered with opts, of the grid
+// cells of lands with a failed process. The renderer has no failed state, so
+// themer.recolor outlines these cells instead.
+func failedCells(state *ViewState, opts sprites.Options) []image.Rectangle {
+	if state == nil {
+		return nil
+	}
+	var cells []image.Rectangle
+	var positions []image.Point
+	for i, land := range state.Lands {
+		for _, proc := range land.AllProcesses() {
+			if proc.State != ProcessStateFailed {
+				continue
+			}
+			if positions == nil {
+				positions = layoutGrid(state.Lands)
+			}
+			pos := positions[i]
+			cells = append(cells, gridRectToPixels(Viewport{X: pos.X, Y: pos.Y, Width: 1, Height: 1}, opts))
+			break
+		}
+	}
+	return cells
+}
+
+// groupByParent splits children into those whose ParentID names one of parents,
+// keyed by that ID, and the rest, keeping their order.
+func groupByParent(parents, children []ProcessView) (map[string][]ProcessView, []ProcessView) {
+	ids := make(map[string]bool, len(parents))
+	for _, p := range parents {
+		ids[p.ID] = true
+	}
+	grouped := make(map[string][]ProcessView)
+	var orphans []ProcessView
+	for _, c := range children {
+		if ids[c.ParentID] {
+			grouped[c.ParentID] = append(grouped[c.ParentID], c)
+		} else {
+			orphans = append(orphans, c)
+		}
+	}
+	return grouped, orphans
+}
+
+// gridRectToPixels maps a region of grid cells to the pixels it covers in a sprite frame.
+func gridRectToPixels(vp Viewport, opts sprites.Options) image.Rectangle {
+	scale := opts.Scale
+	if scale == 0 {
+		scale = 1.0
+	}
+	tile := int(spriteTileSize * scale)
+	origin := image.Pt(spriteGridOrigin+vp.X*tile, spriteGridOrigin+vp.Y*tile)
+	return image.Rectangle{Min: origin, Max: origin.Add(image.Pt(vp.Width*tile, vp.Height*tile))}
+}
+
 // Ensure SpritesStateAdapter implements sprites.State
 var _ sprites.State = (*SpritesStateAdapter)(nil)
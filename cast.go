@@ -0,0 +1,275 @@
+package nimsforestviewer
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Cast v2 protocol constants.
+const (
+	castNamespaceConnection = "urn:x-cast:com.google.cast.tp.connection"
+	castNamespaceHeartbeat  = "urn:x-cast:com.google.cast.tp.heartbeat"
+	castNamespaceReceiver   = "urn:x-cast:com.google.cast.receiver"
+	castNamespaceMedia      = "urn:x-cast:com.google.cast.media"
+
+	castDefaultSender   = "sender-0"
+	castDefaultReceiver = "receiver-0"
+
+	// castMediaReceiverApp is the Default Media Receiver, which can show images.
+	castMediaReceiverApp = "CC1AD845"
+
+	castMaxMessageSize = 64 * 1024
+)
+
+// castMessage is the CastMessage protobuf exchanged with Cast devices.
+// Only string payloads are used.
+type castMessage struct {
+	SourceID      string
+	DestinationID string
+	Namespace     string
+	Payload       string
+}
+
+// marshal encodes the message in protobuf wire format.
+// protocol_version and payload_type are proto2 required fields and are always written.
+func (m castMessage) marshal() []byte {
+	var b []byte
+	b = append(b, 0x08, 0x00) // 1: protocol_version = CASTV2_1_0
+	b = appendProtoString(b, 2, m.SourceID)
+	b = appendProtoString(b, 3, m.DestinationID)
+	b = appendProtoString(b, 4, m.Namespace)
+	b = append(b, 0x28, 0x00) // 5: payload_type = STRING
+	b = appendProtoString(b, 6, m.Payload)
+	return b
+}
+
+// unmarshalCastMessage decodes a CastMessage, skipping fields it doesn't use.
+func unmarshalCastMessage(b []byte) (castMessage, error) {
+	var m castMessage
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return m, fmt.Errorf("invalid field key")
+		}
+		b = b[n:]
+		field, wireType := key>>3, key&7
+
+		switch wireType {
+		case 0: // varint
+			_, n := binary.Uvarint(b)
+			if n <= 0 {
+				return m, fmt.Errorf("invalid varint in field %d", field)
+			}
+			b = b[n:]
+		case 2: // length-delimited
+			size, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < size {
+				return m, fmt.Errorf("invalid length in field %d", field)
+			}
+			value := string(b[n : n+int(size)])
+			b = b[n+int(size):]
+			switch field {
+			case 2:
+				m.SourceID = value
+			case 3:
+				m.DestinationID = value
+			case 4:
+				m.Namespace = value
+			case 6:
+				m.Payload = value
+			}
+		default:
+			return m, fmt.Errorf("unsupported wire type %d in field %d", wireType, field)
+		}
+	}
+	return m, nil
+}
+
+// castConn is a connection to a Cast device that answers heartbeats and tracks receiver status.
+type castConn struct {
+	conn     net.Conn
+	writeMu  sync.Mutex
+	reqMu    sync.Mutex
+	reqID    int
+	statusCh chan castReceiverStatus
+	done     chan struct{}
+	err      error // Read error that ended the connection; valid after done is closed
+}
+
+// castReceiverStatus is the subset of a RECEIVER_STATUS payload used to find the media session.
+type castReceiverStatus struct {
+	Applications []struct {
+		AppID       string `json:"appId"`
+		TransportID string `json:"transportId"`
+	} `json:"applications"`
+}
+
+// dialCast opens a TLS connection to a Cast device and connects to its platform receiver.
+// Cast devices use self-signed certificates, so the certificate is not verified.
+func dialCast(ctx context.Context, addr string) (*castConn, error) {
+	dialer := &tls.Dialer{Config: &tls.Config{InsecureSkipVerify: true}}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &castConn{
+		conn:     conn,
+		statusCh: make(chan castReceiverStatus, 1),
+		done:     make(chan struct{}),
+	}
+	go c.readLoop()
+
+	if err := c.send(castDefaultReceiver, castNamespaceConnection, map[string]any{"type": "CONNECT"}); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// launchMediaReceiver starts the Default Media Receiver and returns its transport ID.
+func (c *castConn) launchMediaReceiver(ctx context.Context) (string, error) {
+	err := c.send(castDefaultReceiver, castNamespaceReceiver, map[string]any{
+		"type":      "LAUNCH",
+		"appId":     castMediaReceiverApp,
+		"requestId": c.nextRequestID(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		select {
+		case status := <-c.statusCh:
+			for _, app := range status.Applications {
+				if app.AppID == castMediaReceiverApp && app.TransportID != "" {
+					// Open a virtual connection to the app before sending it media commands
+					err := c.send(app.TransportID, castNamespaceConnection, map[string]any{"type": "CONNECT"})
+					return app.TransportID, err
+				}
+			}
+		case <-c.done:
+			return "", fmt.Errorf("connection closed: %w", c.err)
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
+
+// loadMedia asks the media receiver to show the content at url.
+func (c *castConn) loadMedia(transportID, url, contentType string) error {
+	return c.send(transportID, castNamespaceMedia, map[string]any{
+		"type":      "LOAD",
+		"requestId": c.nextRequestID(),
+		"autoplay":  true,
+		"media": map[string]any{
+			"contentId":   url,
+			"contentType": contentType,
+			"streamType":  "NONE",
+		},
+	})
+}
+
+func (c *castConn) nextRequestID() int {
+	c.reqMu.Lock()
+	defer c.reqMu.Unlock()
+	c.reqID++
+	return c.reqID
+}
+
+// send writes a JSON payload to the given destination and namespace.
+func (c *castConn) send(destination, namespace string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	msg := castMessage{
+		SourceID:      castDefaultSender,
+		DestinationID: destination,
+		Namespace:     namespace,
+		Payload:       string(data),
+	}.marshal()
+
+	frame := binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(msg)), uint32(len(msg)))
+	frame = append(frame, msg...)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err = c.conn.Write(frame)
+	return err
+}
+
+// readLoop handles incoming messages until the connection fails.
+func (c *castConn) readLoop() {
+	defer close(c.done)
+
+	header := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(c.conn, header); err != nil {
+			c.err = err
+			return
+		}
+		size := binary.BigEndian.Uint32(header)
+		if size > castMaxMessageSize {
+			c.err = fmt.Errorf("message of %d bytes exceeds limit", size)
+			return
+		}
+		body := make([]byte, size)
+		if _, err := io.ReadFull(c.conn, body); err != nil {
+			c.err = err
+			return
+		}
+
+		msg, err := unmarshalCastMessage(body)
+		if err != nil {
+			continue
+		}
+		var payload struct {
+			Type   string             `json:"type"`
+			Status castReceiverStatus `json:"status"`
+		}
+		if err := json.Unmarshal([]byte(msg.Payload), &payload); err != nil {
+			continue
+		}
+
+		switch {
+		case msg.Namespace == castNamespaceHeartbeat && payload.Type == "PING":
+			c.send(msg.SourceID, castNamespaceHeartbeat, map[string]any{"type": "PONG"})
+		case msg.Namespace == castNamespaceReceiver && payload.Type == "RECEIVER_STATUS":
+			// Keep only the newest status
+			select {
+			case <-c.statusCh:
+			default:
+			}
+			c.statusCh <- payload.Status
+		case msg.Namespace == castNamespaceConnection && payload.Type == "CLOSE":
+			c.err = errors.New("closed by device")
+			c.conn.Close()
+			return
+		}
+	}
+}
+
+// Close closes the connection.
+func (c *castConn) Close() error {
+	return c.conn.Close()
+}
+
+// alive reports whether the connection is still usable.
+func (c *castConn) alive() bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,145 @@
+package nimsforestviewer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	smarttv "github.com/nimsforest/nimsforestsmarttv"
+)
+
+// dlnaImageHistory is how many images dlnaImageServer keeps for TVs that fetch late.
+const dlnaImageHistory = 10
+
+// dlnaImageServer serves images of any content type for DLNA renderers to fetch.
+// smarttv.Renderer only serves JPEG, so formats such as PNG go through this instead.
+type dlnaImageServer struct {
+	listener net.Listener
+	server   *http.Server
+	localIP  string
+
+	mu      sync.Mutex
+	images  map[string]dlnaImage
+	order   []string
+	counter uint64
+}
+
+type dlnaImage struct {
+	data        []byte
+	contentType string
+}
+
+func newDLNAImageServer() (*dlnaImageServer, error) {
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return nil, fmt.Errorf("listen: %w", err)
+	}
+
+	s := &dlnaImageServer{
+		listener: listener,
+		localIP:  getLocalIP(),
+		images:   make(map[string]dlnaImage),
+	}
+	s.server = &http.Server{
+		Handler:      http.HandlerFunc(s.serveImage),
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+	go s.server.Serve(listener)
+
+	return s, nil
+}
+
+// store keeps data under a new URL and returns it. Each image gets a unique URL
+// so TVs don't show a cached copy.
+func (s *dlnaImageServer) store(data []byte, contentType, ext string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.counter++
+	path := fmt.Sprintf("/img_%d_%d.%s", s.counter, time.Now().UnixNano(), ext)
+	s.images[path] = dlnaImage{data: data, contentType: contentType}
+	s.order = append(s.order, path)
+	if len(s.order) > dlnaImageHistory {
+		delete(s.images, s.order[0])
+		s.order = s.order[1:]
+	}
+
+	port := s.listener.Addr().(*net.TCPAddr).Port
+	return fmt.Sprintf("http://%s:%d%s", s.localIP, port, path)
+}
+
+func (s *dlnaImageServer) serveImage(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	img, ok := s.images[r.URL.Path]
+	s.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", img.contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(img.data)))
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Write(img.data)
+}
+
+func (s *dlnaImageServer) close() error {
+	return s.server.Close()
+}
+
+// dlnaDisplayImage tells the TV to show the image at url via AVTransport SetAVTransportURI and Play.
+func dlnaDisplayImage(ctx context.Context, tv *smarttv.TV, url, contentType string) error {
+	metadata := fmt.Sprintf(`<DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/"><item id="1" parentID="0" restricted="1"><dc:title>Image</dc:title><upnp:class>object.item.imageItem.photo</upnp:class><res protocolInfo="http-get:*:%s:*">%s</res></item></DIDL-Lite>`,
+		contentType, html.EscapeString(url))
+
+	setURI := fmt.Sprintf(`<InstanceID>0</InstanceID><CurrentURI>%s</CurrentURI><CurrentURIMetaData>%s</CurrentURIMetaData>`,
+		html.EscapeString(url), html.EscapeString(metadata))
+	if err := dlnaSOAP(ctx, tv, "SetAVTransportURI", setURI); err != nil {
+		return fmt.Errorf("set URI: %w", err)
+	}
+	if err := dlnaSOAP(ctx, tv, "Play", `<InstanceID>0</InstanceID><Speed>1</Speed>`); err != nil {
+		return fmt.Errorf("play: %w", err)
+	}
+	return nil
+}
+
+// dlnaSOAP invokes an AVTransport action on the TV's control URL.
+func dlnaSOAP(ctx context.Context, tv *smarttv.TV, action, args string) error {
+	body := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">
+  <s:Body>
+    <u:%[1]s xmlns:u="urn:schemas-upnp-org:service:AVTransport:1">%[2]s</u:%[1]s>
+  </s:Body>
+</s:Envelope>`, action, args)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tv.ControlURL, bytes.NewBufferString(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", fmt.Sprintf(`"urn:schemas-upnp-org:service:AVTransport:1#%s"`, action))
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, respBody)
+	}
+	if bytes.Contains(respBody, []byte("<UPnPError")) {
+		return fmt.Errorf("UPnP error: %s", respBody)
+	}
+	return nil
+}
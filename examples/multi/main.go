@@ -140,7 +140,7 @@ func createMockState() *viewer.ViewState {
 				ID: "land-1", Hostname: "node-alpha",
 				GridX: 0, GridY: 0,
 				IsManaland: false, Occupancy: 0.6,
-				RAMTotal: 16e9, RAMAllocated: 10e9,
+				RAMTotal: 16 * viewer.GiB, RAMAllocated: 10 * viewer.GiB,
 				Trees: []viewer.ProcessView{
 					{ID: "tree-1", Name: "data-parser", Type: "tree", Progress: 0.8},
 				},
@@ -152,7 +152,7 @@ func createMockState() *viewer.ViewState {
 				ID: "land-2", Hostname: "node-beta",
 				GridX: 1, GridY: 0,
 				IsManaland: true, Occupancy: 0.3,
-				RAMTotal: 32e9, RAMAllocated: 10e9,
+				RAMTotal: 32 * viewer.GiB, RAMAllocated: 10 * viewer.GiB,
 				Trees: []viewer.ProcessView{
 					{ID: "tree-2", Name: "gpu-worker", Type: "tree", Progress: 0.9},
 				},
@@ -161,7 +161,7 @@ func createMockState() *viewer.ViewState {
 				ID: "land-3", Hostname: "node-gamma",
 				GridX: 0, GridY: 1,
 				IsManaland: false, Occupancy: 0.4,
-				RAMTotal: 8e9, RAMAllocated: 3e9,
+				RAMTotal: 8 * viewer.GiB, RAMAllocated: 3 * viewer.GiB,
 				Treehouses: []viewer.ProcessView{
 					{ID: "th-1", Name: "lua-script", Type: "treehouse", Progress: 1.0},
 				},
@@ -170,13 +170,13 @@ func createMockState() *viewer.ViewState {
 				ID: "land-4", Hostname: "node-delta",
 				GridX: 1, GridY: 1,
 				IsManaland: false, Occupancy: 0.2,
-				RAMTotal: 16e9, RAMAllocated: 3e9,
+				RAMTotal: 16 * viewer.GiB, RAMAllocated: 3 * viewer.GiB,
 			},
 		},
 		Summary: viewer.SummaryView{
 			TotalLands: 4, TotalManalands: 1,
 			TotalTrees: 2, TotalTreehouses: 1, TotalNims: 1,
-			TotalRAM: 72e9, AllocatedRAM: 26e9,
+			TotalRAM: 72 * viewer.GiB, AllocatedRAM: 26 * viewer.GiB,
 		},
 	}
 }
@@ -72,8 +72,8 @@ func createMockState() *viewer.ViewState {
 				GridY:        0,
 				IsManaland:   false,
 				Occupancy:    0.6,
-				RAMTotal:     16 * 1024 * 1024 * 1024,
-				RAMAllocated: 10 * 1024 * 1024 * 1024,
+				RAMTotal:     16 * viewer.GiB,
+				RAMAllocated: 10 * viewer.GiB,
 				Trees: []viewer.ProcessView{
 					{ID: "tree-1", Name: "data-parser", Type: "tree", Progress: 0.8},
 				},
@@ -88,8 +88,8 @@ func createMockState() *viewer.ViewState {
 				GridY:        0,
 				IsManaland:   true,
 				Occupancy:    0.3,
-				RAMTotal:     32 * 1024 * 1024 * 1024,
-				RAMAllocated: 10 * 1024 * 1024 * 1024,
+				RAMTotal:     32 * viewer.GiB,
+				RAMAllocated: 10 * viewer.GiB,
 				Trees: []viewer.ProcessView{
 					{ID: "tree-2", Name: "gpu-worker", Type: "tree", Progress: 0.9},
 				},
@@ -101,8 +101,8 @@ func createMockState() *viewer.ViewState {
 				GridY:        1,
 				IsManaland:   false,
 				Occupancy:    0.4,
-				RAMTotal:     8 * 1024 * 1024 * 1024,
-				RAMAllocated: 3 * 1024 * 1024 * 1024,
+				RAMTotal:     8 * viewer.GiB,
+				RAMAllocated: 3 * viewer.GiB,
 				Treehouses: []viewer.ProcessView{
 					{ID: "th-1", Name: "lua-script", Type: "treehouse", Progress: 1.0},
 				},
@@ -114,8 +114,8 @@ func createMockState() *viewer.ViewState {
 			TotalTrees:      2,
 			TotalTreehouses: 1,
 			TotalNims:       1,
-			TotalRAM:        56 * 1024 * 1024 * 1024,
-			AllocatedRAM:    23 * 1024 * 1024 * 1024,
+			TotalRAM:        56 * viewer.GiB,
+			AllocatedRAM:    23 * viewer.GiB,
 		},
 	}
 }
@@ -0,0 +1,184 @@
+package nimsforestviewer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// jpegPipeTimeout bounds how long jpegPipe waits for ffmpeg to return a frame.
+const jpegPipeTimeout = 10 * time.Second
+
+// jpegPipe is a long-lived ffmpeg process that turns raw RGBA frames written to
+// its stdin into JPEGs read back from its stdout, avoiding a process spawn per frame.
+type jpegPipe struct {
+	cmd           *exec.Cmd
+	stdin         io.WriteCloser
+	stdoutFile    *os.File
+	stdout        *bufio.Reader
+	width, height int
+}
+
+// startJPEGPipe starts ffmpeg for frames of the given size.
+func startJPEGPipe(ffmpeg string, width, height, qscale int) (*jpegPipe, error) {
+	cmd := exec.Command(ffmpeg,
+		"-loglevel", "error",
+		"-f", "rawvideo",
+		"-pix_fmt", "rgba",
+		"-s", fmt.Sprintf("%dx%d", width, height),
+		"-i", "pipe:0",
+		"-f", "image2pipe",
+		"-c:v", "mjpeg",
+		"-pix_fmt", "yuvj420p",
+		"-q:v", strconv.Itoa(qscale),
+		"-flush_packets", "1",
+		"pipe:1",
+	)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	// An os.Pipe rather than StdoutPipe, so reads can time out
+	stdoutRead, stdoutWrite, err := os.Pipe()
+	if err != nil {
+		stdin.Close()
+		return nil, err
+	}
+	cmd.Stdout = stdoutWrite
+
+	if err := cmd.Start(); err != nil {
+		stdin.Close()
+		stdoutRead.Close()
+		stdoutWrite.Close()
+		return nil, fmt.Errorf("start ffmpeg: %w", err)
+	}
+	stdoutWrite.Close() // The child holds its own copy
+
+	return &jpegPipe{
+		cmd:        cmd,
+		stdin:      stdin,
+		stdoutFile: stdoutRead,
+		stdout:     bufio.NewReaderSize(stdoutRead, 256*1024),
+		width:      width,
+		height:     height,
+	}, nil
+}
+
+// encode sends one frame through ffmpeg and returns the JPEG it produces.
+// After an error the pipe is unusable and must be closed.
+func (p *jpegPipe) encode(img *image.RGBA) ([]byte, error) {
+	if img.Bounds().Dx() != p.width || img.Bounds().Dy() != p.height {
+		return nil, fmt.Errorf("frame size %v does not match pipe size %dx%d", img.Bounds().Size(), p.width, p.height)
+	}
+	if _, err := p.stdin.Write(img.Pix); err != nil {
+		return nil, fmt.Errorf("write frame: %w", err)
+	}
+
+	p.stdoutFile.SetReadDeadline(time.Now().Add(jpegPipeTimeout))
+	data, err := readJPEG(p.stdout)
+	if err != nil {
+		return nil, fmt.Errorf("read JPEG: %w", err)
+	}
+	return data, nil
+}
+
+// close stops ffmpeg, killing it if it doesn't exit promptly after its input closes.
+func (p *jpegPipe) close() error {
+	p.stdin.Close()
+	done := make(chan error, 1)
+	go func() { done <- p.cmd.Wait() }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(2 * time.Second):
+		p.cmd.Process.Kill()
+		err = <-done
+	}
+	p.stdoutFile.Close()
+	return err
+}
+
+// readJPEG reads one JPEG image, SOI through EOI, from a stream of concatenated JPEGs.
+func readJPEG(r *bufio.Reader) ([]byte, error) {
+	var buf bytes.Buffer
+
+	soi := make([]byte, 2)
+	if _, err := io.ReadFull(r, soi); err != nil {
+		return nil, err
+	}
+	if soi[0] != 0xFF || soi[1] != 0xD8 {
+		return nil, errors.New("missing SOI marker")
+	}
+	buf.Write(soi)
+
+	// Header segments carry explicit lengths up to and including SOS
+	for {
+		marker := make([]byte, 4)
+		if _, err := io.ReadFull(r, marker[:2]); err != nil {
+			return nil, err
+		}
+		if marker[0] != 0xFF {
+			return nil, fmt.Errorf("invalid marker 0x%02x%02x", marker[0], marker[1])
+		}
+		if _, err := io.ReadFull(r, marker[2:]); err != nil {
+			return nil, err
+		}
+		length := int(marker[2])<<8 | int(marker[3])
+		if length < 2 {
+			return nil, fmt.Errorf("invalid segment length %d", length)
+		}
+		buf.Write(marker)
+		if _, err := io.CopyN(&buf, r, int64(length-2)); err != nil {
+			return nil, err
+		}
+		if marker[1] == 0xDA { // SOS: entropy-coded data follows
+			break
+		}
+	}
+
+	// In entropy-coded data 0xFF is followed by 0x00 (stuffing) or a restart
+	// marker; anything else ends the scan. ffmpeg's MJPEG output has a single scan.
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteByte(b)
+		if b != 0xFF {
+			continue
+		}
+		next, err := r.ReadByte()
+		for err == nil && next == 0xFF { // Fill bytes before a marker
+			buf.WriteByte(next)
+			next, err = r.ReadByte()
+		}
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteByte(next)
+		if next == 0xD9 {
+			return buf.Bytes(), nil
+		}
+	}
+}
+
+// runMagick re-encodes a JPEG through imagemagick, which writes a JFIF header.
+func runMagick(magick string, data []byte) ([]byte, error) {
+	cmd := exec.Command(magick, "jpeg:-", "jpeg:-")
+	cmd.Stdin = bytes.NewReader(data)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
@@ -2,12 +2,15 @@ module github.com/nimsforest/nimsforestviewer
 
 go 1.25.5
 
+require (
+	github.com/nimsforest/nimsforestsmarttv v0.0.0-20260109180238-9549a319e407
+	github.com/nimsforest/nimsforestsprites v0.0.0-20260109145100-c7cd58a99f3a
+)
+
 require (
 	github.com/ebitengine/purego v0.6.0 // indirect
 	github.com/hajimehoshi/ebiten/v2 v2.6.6 // indirect
 	github.com/jezek/xgb v1.1.0 // indirect
-	github.com/nimsforest/nimsforestsmarttv v0.0.0-20260109180238-9549a319e407 // indirect
-	github.com/nimsforest/nimsforestsprites v0.0.0-20260109145100-c7cd58a99f3a // indirect
 	golang.org/x/exp/shiny v0.0.0-20230817173708-d852ddb80c63 // indirect
 	golang.org/x/image v0.12.0 // indirect
 	golang.org/x/mobile v0.0.0-20230922142353-e2f452493d57 // indirect
@@ -0,0 +1,213 @@
+package nimsforestviewer
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// AspectMode decides how a frame is fitted to an output resolution with a
+// different aspect ratio.
+type AspectMode string
+
+const (
+	// AspectFit scales the frame to fit inside the output and letterboxes the rest.
+	AspectFit AspectMode = "fit"
+	// AspectFill scales the frame to cover the output and crops the overflow.
+	AspectFill AspectMode = "fill"
+	// AspectStretch scales each axis independently, distorting the frame.
+	AspectStretch AspectMode = "stretch"
+)
+
+// ImageScaler draws the sr part of src into the dr part of dst, resampling it
+// to fit. ScaleNearest, ScaleBilinear and ScaleArea are provided; scalers from
+// golang.org/x/image/draw can be used through FromDrawScaler.
+type ImageScaler func(dst *image.RGBA, dr image.Rectangle, src image.Image, sr image.Rectangle)
+
+// DrawScaler is the method set of golang.org/x/image/draw.Scaler, with O standing
+// for that package's Options type.
+//
+// This package doesn't depend on x/image, so it can't take draw.Scaler or
+// draw.Interpolator itself. A plain interface can't stand in for them either:
+// Scale's last parameter is *draw.Options, which can only be named by importing
+// x/image. The type parameter lets the compiler infer it from the scaler passed
+// to FromDrawScaler, so x/image's scalers satisfy DrawScaler as they are.
+type DrawScaler[O any] interface {
+	Scale(dst draw.Image, dr image.Rectangle, src image.Image, sr image.Rectangle, op draw.Op, opts *O)
+}
+
+// FromDrawScaler returns an ImageScaler that scales with s, such as
+// xdraw.CatmullRom or xdraw.ApproxBiLinear from golang.org/x/image/draw:
+//
+//	viewer.WithImageScaler(viewer.FromDrawScaler(xdraw.CatmullRom))
+func FromDrawScaler[O any](s DrawScaler[O]) ImageScaler {
+	return func(dst *image.RGBA, dr image.Rectangle, src image.Image, sr image.Rectangle) {
+		s.Scale(dst, dr, src, sr, draw.Src, nil)
+	}
+}
+
+// fitImage returns img scaled to width x height by scale according to mode,
+// or by ScaleBilinear if scale is nil. Letterbox bars are filled with bg.
+// img is returned unchanged when it already has the size.
+func fitImage(img image.Image, width, height int, mode AspectMode, bg color.Color, scale ImageScaler) image.Image {
+	src := img.Bounds()
+	if width <= 0 || height <= 0 || src.Empty() || (src.Dx() == width && src.Dy() == height) {
+		return img
+	}
+
+	if scale == nil {
+		scale = ScaleBilinear
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	switch mode {
+	case AspectStretch:
+		scale(dst, dst.Bounds(), img, src)
+	case AspectFill:
+		// Crop the source to the output's aspect ratio, centered
+		crop := src
+		if src.Dx()*height > src.Dy()*width {
+			w := src.Dy() * width / height
+			crop.Min.X += (src.Dx() - w) / 2
+			crop.Max.X = crop.Min.X + w
+		} else {
+			h := src.Dx() * height / width
+			crop.Min.Y += (src.Dy() - h) / 2
+			crop.Max.Y = crop.Min.Y + h
+		}
+		scale(dst, dst.Bounds(), img, crop)
+	default: // AspectFit
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+		w, h := width, height
+		if src.Dx()*height > src.Dy()*width {
+			h = src.Dy() * width / src.Dx()
+		} else {
+			w = src.Dx() * height / src.Dy()
+		}
+		x, y := (width-w)/2, (height-h)/2
+		scale(dst, image.Rect(x, y, x+w, y+h), img, src)
+	}
+	return dst
+}
+
+// ScaleNearest is an ImageScaler that picks the nearest source pixel. It is the
+// fastest, and keeps pixel art sharp at whole-number scale factors.
+func ScaleNearest(dst *image.RGBA, dr image.Rectangle, src image.Image, sr image.Rectangle) {
+	if dr.Empty() || sr.Empty() {
+		return
+	}
+	rgba := rgbaSource(src, sr)
+	for y := dr.Min.Y; y < dr.Max.Y; y++ {
+		sy := sr.Min.Y + (y-dr.Min.Y)*sr.Dy()/dr.Dy()
+		for x := dr.Min.X; x < dr.Max.X; x++ {
+			sx := sr.Min.X + (x-dr.Min.X)*sr.Dx()/dr.Dx()
+			s := rgba.PixOffset(sx, sy)
+			copy(dst.Pix[dst.PixOffset(x, y):][:4], rgba.Pix[s:s+4])
+		}
+	}
+}
+
+// ScaleBilinear is an ImageScaler that interpolates between the four nearest
+// source pixels. It is the default, and suits small scale factors; text gets
+// jagged when shrinking by much more than half.
+func ScaleBilinear(dst *image.RGBA, dr image.Rectangle, src image.Image, sr image.Rectangle) {
+	if dr.Empty() || sr.Empty() {
+		return
+	}
+	rgba := rgbaSource(src, sr)
+
+	sx := float64(sr.Dx()) / float64(dr.Dx())
+	sy := float64(sr.Dy()) / float64(dr.Dy())
+	for y := dr.Min.Y; y < dr.Max.Y; y++ {
+		fy := (float64(y-dr.Min.Y)+0.5)*sy - 0.5
+		y0, wy := splitCoord(fy, sr.Min.Y, sr.Max.Y)
+		y1 := min(y0+1, sr.Max.Y-1)
+		for x := dr.Min.X; x < dr.Max.X; x++ {
+			fx := (float64(x-dr.Min.X)+0.5)*sx - 0.5
+			x0, wx := splitCoord(fx, sr.Min.X, sr.Max.X)
+			x1 := min(x0+1, sr.Max.X-1)
+
+			p00 := rgba.PixOffset(x0, y0)
+			p10 := rgba.PixOffset(x1, y0)
+			p01 := rgba.PixOffset(x0, y1)
+			p11 := rgba.PixOffset(x1, y1)
+			d := dst.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				top := float64(rgba.Pix[p00+c])*(1-wx) + float64(rgba.Pix[p10+c])*wx
+				bottom := float64(rgba.Pix[p01+c])*(1-wx) + float64(rgba.Pix[p11+c])*wx
+				dst.Pix[d+c] = uint8(top*(1-wy) + bottom*wy + 0.5)
+			}
+		}
+	}
+}
+
+// ScaleArea is an ImageScaler that averages every source pixel a destination
+// pixel covers, weighted by coverage. It is the slowest, and keeps text and thin
+// lines legible when downscaling, e.g. a 1080p frame for a 720p panel.
+// Upscaling falls back to ScaleBilinear.
+func ScaleArea(dst *image.RGBA, dr image.Rectangle, src image.Image, sr image.Rectangle) {
+	if dr.Empty() || sr.Empty() {
+		return
+	}
+	if dr.Dx() >= sr.Dx() && dr.Dy() >= sr.Dy() {
+		ScaleBilinear(dst, dr, src, sr)
+		return
+	}
+	rgba := rgbaSource(src, sr)
+
+	sx := float64(sr.Dx()) / float64(dr.Dx())
+	sy := float64(sr.Dy()) / float64(dr.Dy())
+	for y := dr.Min.Y; y < dr.Max.Y; y++ {
+		y0 := float64(y-dr.Min.Y) * sy
+		y1 := y0 + sy
+		for x := dr.Min.X; x < dr.Max.X; x++ {
+			x0 := float64(x-dr.Min.X) * sx
+			x1 := x0 + sx
+
+			var sum [4]float64
+			var total float64
+			for j := int(y0); float64(j) < y1 && j < sr.Dy(); j++ {
+				wy := min(y1, float64(j+1)) - max(y0, float64(j))
+				for i := int(x0); float64(i) < x1 && i < sr.Dx(); i++ {
+					w := wy * (min(x1, float64(i+1)) - max(x0, float64(i)))
+					p := rgba.PixOffset(sr.Min.X+i, sr.Min.Y+j)
+					for c := 0; c < 4; c++ {
+						sum[c] += float64(rgba.Pix[p+c]) * w
+					}
+					total += w
+				}
+			}
+			if total == 0 {
+				continue
+			}
+			d := dst.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				dst.Pix[d+c] = uint8(sum[c]/total + 0.5)
+			}
+		}
+	}
+}
+
+// rgbaSource returns src as an *image.RGBA covering at least sr, converting it if needed.
+func rgbaSource(src image.Image, sr image.Rectangle) *image.RGBA {
+	if rgba, ok := src.(*image.RGBA); ok {
+		return rgba
+	}
+	rgba := image.NewRGBA(sr)
+	draw.Draw(rgba, sr, src, sr.Min, draw.Src)
+	return rgba
+}
+
+// splitCoord maps a source coordinate, relative to lo, to a pixel index within
+// [lo, hi) and the weight of the following pixel.
+func splitCoord(f float64, lo, hi int) (int, float64) {
+	if f < 0 {
+		return lo, 0
+	}
+	i := int(f)
+	w := f - float64(i)
+	i += lo
+	if i >= hi-1 {
+		return hi - 1, 0
+	}
+	return i, w
+}
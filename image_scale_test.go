@@ -0,0 +1,38 @@
+package nimsforestviewer
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// fakeOptions and fakeKernel mimic golang.org/x/image/draw's Options and Kernel.
+type fakeOptions struct{}
+
+type fakeKernel struct {
+	calls *int
+}
+
+func (k fakeKernel) Scale(dst draw.Image, dr image.Rectangle, src image.Image, sr image.Rectangle, op draw.Op, opts *fakeOptions) {
+	*k.calls++
+	draw.Draw(dst, dr, image.NewUniform(color.RGBA{255, 0, 0, 255}), image.Point{}, op)
+}
+
+func TestFromDrawScaler(t *testing.T) {
+	var calls int
+	scale := FromDrawScaler(fakeKernel{&calls}) // O is inferred from the Scale method
+
+	src := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	got := fitImage(src, 20, 20, AspectFit, color.RGBA{0, 0, 0, 255}, scale)
+	if calls != 1 {
+		t.Fatalf("scaler called %d times, want 1", calls)
+	}
+	// Fitting 2:1 into a square letterboxes rows 0-4 and 15-19
+	if c := got.At(10, 10); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("center = %v, want the scaler's output", c)
+	}
+	if c := got.At(10, 2); c != (color.RGBA{0, 0, 0, 255}) {
+		t.Errorf("letterbox = %v, want the background", c)
+	}
+}
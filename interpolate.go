@@ -0,0 +1,45 @@
+package nimsforestviewer
+
+// InterpolateState returns a copy of to with each land's Occupancy and each
+// process's Progress moved a fraction t (0-1) of the way from their values in
+// from. Lands are matched by ID, and processes by ID within their land; lands
+// and processes only present in to keep their values, and those only in from
+// are dropped. Everything else, including Summary, comes from to.
+func InterpolateState(from, to *ViewState, t float64) *ViewState {
+	state := to.Clone()
+	if from == nil || state == nil {
+		return state
+	}
+	t = clamp01(t)
+
+	prevLands := make(map[string]*LandView, len(from.Lands))
+	for i := range from.Lands {
+		prevLands[from.Lands[i].ID] = &from.Lands[i]
+	}
+
+	for i := range state.Lands {
+		land := &state.Lands[i]
+		prev, ok := prevLands[land.ID]
+		if !ok {
+			continue
+		}
+		land.Occupancy = lerp(prev.Occupancy, land.Occupancy, t)
+
+		prevProgress := make(map[string]float64)
+		for _, proc := range prev.AllProcesses() {
+			prevProgress[proc.ID] = proc.Progress
+		}
+		for _, procs := range [][]ProcessView{land.Trees, land.Treehouses, land.Nims} {
+			for j := range procs {
+				if p, ok := prevProgress[procs[j].ID]; ok {
+					procs[j].Progress = lerp(p, procs[j].Progress, t)
+				}
+			}
+		}
+	}
+	return state
+}
+
+func lerp(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
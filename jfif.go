@@ -0,0 +1,61 @@
+package nimsforestviewer
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+)
+
+// jfifQuality is the default JPEG quality of the in-process JFIF encoder.
+const jfifQuality = 90
+
+// jfifAPP0 is a JFIF 1.01 APP0 segment: no density units, 1:1 pixel aspect, no thumbnail.
+var jfifAPP0 = []byte{
+	0xFF, 0xE0, // APP0 marker
+	0x00, 0x10, // Segment length (16 bytes, including these two)
+	'J', 'F', 'I', 'F', 0x00, // Identifier
+	0x01, 0x01, // Version 1.01
+	0x00,       // Density units: none, aspect ratio only
+	0x00, 0x01, // X density
+	0x00, 0x01, // Y density
+	0x00, 0x00, // No thumbnail
+}
+
+// encodeJFIF encodes img as a JFIF-compliant baseline JPEG without shelling out.
+//
+// The stdlib encoder already writes baseline (SOF0) JPEGs with 4:2:0 chroma
+// subsampling, but omits the JFIF APP0 header some TVs insist on (notably the
+// JVC sets that motivated the ffmpeg + imagemagick path). This inserts that
+// header directly after the SOI marker.
+//
+// This path has not yet been verified on physical TVs. If a TV rejects its
+// output, install ffmpeg (and optionally imagemagick) to use the external path.
+// A quality of 0 uses jfifQuality.
+func encodeJFIF(img image.Image, quality int) ([]byte, error) {
+	if quality == 0 {
+		quality = jfifQuality
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return insertJFIFHeader(buf.Bytes())
+}
+
+// insertJFIFHeader adds a JFIF APP0 segment after the SOI marker of a JPEG stream.
+func insertJFIFHeader(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, fmt.Errorf("not a JPEG stream")
+	}
+	// Already JFIF
+	if data[2] == 0xFF && data[3] == 0xE0 {
+		return data, nil
+	}
+
+	out := make([]byte, 0, len(data)+len(jfifAPP0))
+	out = append(out, data[:2]...)
+	out = append(out, jfifAPP0...)
+	out = append(out, data[2:]...)
+	return out, nil
+}
@@ -0,0 +1,45 @@
+package nimsforestviewer
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"testing"
+)
+
+func TestEncodeJFIF(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 32, 16))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i)
+	}
+	data, err := encodeJFIF(img, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, append([]byte{0xFF, 0xD8}, jfifAPP0...)) {
+		t.Fatalf("stream starts % x, want SOI followed by the JFIF APP0 segment", data[:min(len(data), 20)])
+	}
+	decoded, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("output doesn't decode: %v", err)
+	}
+	if b := decoded.Bounds(); b != img.Bounds() {
+		t.Errorf("decoded bounds = %v, want %v", b, img.Bounds())
+	}
+}
+
+func TestInsertJFIFHeader(t *testing.T) {
+	if _, err := insertJFIFHeader([]byte("not a jpeg")); err == nil {
+		t.Error("insertJFIFHeader accepted data without an SOI marker")
+	}
+
+	// A stream that is already JFIF is returned as is
+	jfif := append(append([]byte{0xFF, 0xD8}, jfifAPP0...), 0xFF, 0xD9)
+	got, err := insertJFIFHeader(jfif)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, jfif) {
+		t.Errorf("JFIF stream changed: % x", got)
+	}
+}
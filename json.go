@@ -2,13 +2,41 @@ package nimsforestviewer
 
 import (
 	"encoding/json"
-	"math"
+	"image"
+	"reflect"
+	"sort"
+	"time"
 )
 
+// SchemaVersion is the version of the WorldJSON payload shape, sent as its "version"
+// field so independently deployed clients can detect incompatible payloads.
+// It is incremented whenever fields are added, removed or change meaning.
+//
+// Version 2 added the process "state" field.
+// Version 3 populated the process "subjects" field and added "edges".
+// Version 4 added the summary "last_update" and "stale_after" fields.
+// Version 5 added the process "parent_id" field.
+// Version 6 added the summary "total_ram_human" and "ram_allocated_human" fields.
+// Version 7 added the land "process_count" and "hidden_processes" fields.
+const SchemaVersion = 7
+
 // WorldJSON is the JSON representation of ViewState for the web frontend.
 type WorldJSON struct {
-	Lands   []LandJSON  `json:"lands"`
-	Summary SummaryJSON `json:"summary"`
+	Version     int         `json:"version"`      // SchemaVersion
+	GeneratedAt time.Time   `json:"generated_at"` // When the payload was built
+	Lands       []LandJSON  `json:"lands"`
+	Edges       []EdgeJSON  `json:"edges"` // Processes linked by a shared subject
+	Summary     SummaryJSON `json:"summary"`
+}
+
+// EdgeJSON links two processes that share a message subject.
+// Edges are undirected; From is the process listed first in the world.
+type EdgeJSON struct {
+	Subject  string `json:"subject"`
+	From     string `json:"from"` // Process ID
+	FromLand string `json:"from_land"`
+	To       string `json:"to"`
+	ToLand   string `json:"to_land"`
 }
 
 // LandJSON is the JSON representation of a Land tile.
@@ -22,12 +50,18 @@ type LandJSON struct {
 	GPUVram      uint64        `json:"gpu_vram,omitempty"`
 	GPUTflops    float64       `json:"gpu_tflops,omitempty"`
 	Occupancy    float64       `json:"occupancy"`
+	Status       string        `json:"status"` // "ok", "warn" or "critical"
 	IsManaland   bool          `json:"is_manaland"`
 	GridX        int           `json:"grid_x"`
 	GridY        int           `json:"grid_y"`
 	Trees        []ProcessJSON `json:"trees"`
 	Treehouses   []ProcessJSON `json:"treehouses"`
 	Nims         []ProcessJSON `json:"nims"`
+
+	// ProcessCount is the land's true number of processes. With a process limit,
+	// HiddenProcesses of them are folded into one process with ID "_more".
+	ProcessCount    int `json:"process_count"`
+	HiddenProcesses int `json:"hidden_processes,omitempty"`
 }
 
 // ProcessJSON is the JSON representation of a process.
@@ -37,7 +71,9 @@ type ProcessJSON struct {
 	RAMAllocated uint64   `json:"ram_allocated"`
 	Type         string   `json:"type"`
 	Progress     float64  `json:"progress,omitempty"`
+	State        string   `json:"state,omitempty"`
 	Subjects     []string `json:"subjects,omitempty"`
+	ParentID     string   `json:"parent_id,omitempty"` // ID of the process this one runs inside
 	ScriptPath   string   `json:"script_path,omitempty"`
 	AIEnabled    bool     `json:"ai_enabled,omitempty"`
 	Model        string   `json:"model,omitempty"`
@@ -53,56 +89,150 @@ type SummaryJSON struct {
 	TotalRAM       uint64  `json:"total_ram"`
 	RAMAllocated   uint64  `json:"ram_allocated"`
 	Occupancy      float64 `json:"occupancy"`
+
+	// TotalRAM and RAMAllocated formatted with Bytes, e.g. "16.0 GiB".
+	TotalRAMHuman     string `json:"total_ram_human"`
+	RAMAllocatedHuman string `json:"ram_allocated_human"`
+
+	// Set by WebTarget: when it last received state, and after how many seconds
+	// without an update the data should be considered stale.
+	LastUpdate time.Time `json:"last_update,omitzero"`
+	StaleAfter float64   `json:"stale_after,omitempty"`
+}
+
+// WorldDiffJSON is the JSON representation of the changes between two versions
+// of the world. When Full is true, Lands holds the complete model and the client
+// should discard its local copy.
+type WorldDiffJSON struct {
+	Version uint64      `json:"version"`
+	Since   uint64      `json:"since"`
+	Full    bool        `json:"full"`
+	Lands   []LandJSON  `json:"lands"`
+	Removed []string    `json:"removed,omitempty"`
+	Summary SummaryJSON `json:"summary"`
+}
+
+// HistoryJSON is the JSON representation of recent summary samples, oldest first.
+type HistoryJSON struct {
+	Samples []HistorySampleJSON `json:"samples"`
+}
+
+// HistorySampleJSON is the summary captured at one update.
+type HistorySampleJSON struct {
+	Timestamp time.Time   `json:"timestamp"`
+	Summary   SummaryJSON `json:"summary"`
+}
+
+// LandsPageJSON is one page of lands served by /api/lands.
+type LandsPageJSON struct {
+	Total  int        `json:"total"` // Number of lands across all pages
+	Offset int        `json:"offset"`
+	Limit  int        `json:"limit"`
+	Lands  []LandJSON `json:"lands"`
 }
 
 // ViewStateToJSON converts a ViewState to WorldJSON for the web frontend.
 func ViewStateToJSON(state *ViewState) WorldJSON {
 	if state == nil {
-		return WorldJSON{}
-	}
-
-	// Calculate grid positions if not already set
-	gridSize := int(math.Ceil(math.Sqrt(float64(len(state.Lands)))))
-	if gridSize < 1 {
-		gridSize = 1
+		return WorldJSON{Version: SchemaVersion, GeneratedAt: time.Now()}
 	}
 
+	positions := layoutGrid(state.Lands)
 	landsJSON := make([]LandJSON, len(state.Lands))
 	for i, land := range state.Lands {
-		// Use existing grid positions if set, otherwise calculate
-		gridX, gridY := land.GridX, land.GridY
-		if gridX == 0 && gridY == 0 && i > 0 {
-			gridX = i % gridSize
-			gridY = i / gridSize
+		landsJSON[i] = landToJSON(land, positions[i])
+	}
+
+	return WorldJSON{
+		Version:     SchemaVersion,
+		GeneratedAt: time.Now(),
+		Lands:       landsJSON,
+		Edges:       subjectEdges(state.Lands),
+		Summary:     summaryToJSON(state.Summary),
+	}
+}
+
+// landToJSON converts one land placed at pos on the grid.
+func landToJSON(land LandView, pos image.Point) LandJSON {
+	return LandJSON{
+		ID:           land.ID,
+		Hostname:     land.Hostname,
+		RAMTotal:     land.RAMTotal,
+		RAMAllocated: land.RAMAllocated,
+		Occupancy:    land.Occupancy,
+		Status:       landStatus(&land),
+		IsManaland:   land.IsManaland,
+		GridX:        pos.X,
+		GridY:        pos.Y,
+		Trees:        processViewsToJSON(land.Trees, "tree"),
+		Treehouses:   processViewsToJSON(land.Treehouses, "treehouse"),
+		Nims:         processViewsToJSON(land.Nims, "nim"),
+
+		ProcessCount:    processCount(&land),
+		HiddenProcesses: land.HiddenProcesses,
+	}
+}
+
+// processCount returns how many processes the land had before any were folded
+// into a MoreProcessesID marker.
+func processCount(land *LandView) int {
+	n := len(land.Trees) + len(land.Treehouses) + len(land.Nims)
+	if land.HiddenProcesses > 0 {
+		n += land.HiddenProcesses - 1 // The marker stands for the hidden ones
+	}
+	return n
+}
+
+// subjectEdges links the processes that share a subject as a star: the first
+// process listed for a subject gets one edge to each of the others, so a subject
+// shared by n processes yields n-1 edges rather than one per pair. Edges are
+// ordered by subject and then by the order the processes appear in lands.
+func subjectEdges(lands []LandView) []EdgeJSON {
+	type endpoint struct{ proc, land string }
+	bySubject := make(map[string][]endpoint)
+	for _, land := range lands {
+		for _, proc := range land.AllProcesses() {
+			for _, subject := range proc.Subjects {
+				bySubject[subject] = append(bySubject[subject], endpoint{proc.ID, land.ID})
+			}
 		}
+	}
+
+	subjects := make([]string, 0, len(bySubject))
+	for subject := range bySubject {
+		subjects = append(subjects, subject)
+	}
+	sort.Strings(subjects)
 
-		landsJSON[i] = LandJSON{
-			ID:           land.ID,
-			Hostname:     land.Hostname,
-			RAMTotal:     land.RAMTotal,
-			RAMAllocated: land.RAMAllocated,
-			Occupancy:    land.Occupancy,
-			IsManaland:   land.IsManaland,
-			GridX:        gridX,
-			GridY:        gridY,
-			Trees:        processViewsToJSON(land.Trees, "tree"),
-			Treehouses:   processViewsToJSON(land.Treehouses, "treehouse"),
-			Nims:         processViewsToJSON(land.Nims, "nim"),
+	edges := []EdgeJSON{}
+	for _, subject := range subjects {
+		ends := bySubject[subject]
+		for _, end := range ends[1:] {
+			edges = append(edges, EdgeJSON{
+				Subject:  subject,
+				From:     ends[0].proc,
+				FromLand: ends[0].land,
+				To:       end.proc,
+				ToLand:   end.land,
+			})
 		}
 	}
+	return edges
+}
 
-	return WorldJSON{
-		Lands: landsJSON,
-		Summary: SummaryJSON{
-			LandCount:      state.Summary.TotalLands,
-			ManalandCount:  state.Summary.TotalManalands,
-			TreeCount:      state.Summary.TotalTrees,
-			TreehouseCount: state.Summary.TotalTreehouses,
-			NimCount:       state.Summary.TotalNims,
-			TotalRAM:       state.Summary.TotalRAM,
-			RAMAllocated:   state.Summary.AllocatedRAM,
-			Occupancy:      calculateOccupancy(state.Summary.AllocatedRAM, state.Summary.TotalRAM),
-		},
+func summaryToJSON(s SummaryView) SummaryJSON {
+	return SummaryJSON{
+		LandCount:      s.TotalLands,
+		ManalandCount:  s.TotalManalands,
+		TreeCount:      s.TotalTrees,
+		TreehouseCount: s.TotalTreehouses,
+		NimCount:       s.TotalNims,
+		TotalRAM:       s.TotalRAM,
+		RAMAllocated:   s.AllocatedRAM,
+		Occupancy:      calculateOccupancy(s.AllocatedRAM, s.TotalRAM),
+
+		TotalRAMHuman:     Bytes(s.TotalRAM),
+		RAMAllocatedHuman: Bytes(s.AllocatedRAM),
 	}
 }
 
@@ -115,6 +245,65 @@ func processViewsToJSON(processes []ProcessView, procType string) []ProcessJSON
 			RAMAllocated: p.RAMAllocated,
 			Type:         procType,
 			Progress:     p.Progress,
+			State:        p.State,
+			Subjects:     p.Subjects,
+			ParentID:     p.ParentID,
+		}
+	}
+	return result
+}
+
+// JSONToViewState converts WorldJSON, such as a payload produced by another viewer,
+// back into a ViewState. Lands keep their grid positions.
+func JSONToViewState(world WorldJSON) *ViewState {
+	state := &ViewState{
+		Lands: make([]LandView, len(world.Lands)),
+		Summary: SummaryView{
+			TotalLands:      world.Summary.LandCount,
+			TotalManalands:  world.Summary.ManalandCount,
+			TotalTrees:      world.Summary.TreeCount,
+			TotalTreehouses: world.Summary.TreehouseCount,
+			TotalNims:       world.Summary.NimCount,
+			TotalRAM:        world.Summary.TotalRAM,
+			AllocatedRAM:    world.Summary.RAMAllocated,
+		},
+	}
+	for i, land := range world.Lands {
+		state.Lands[i] = LandView{
+			ID:              land.ID,
+			Hostname:        land.Hostname,
+			GridX:           land.GridX,
+			GridY:           land.GridY,
+			HasGridPosition: true,
+			IsManaland:      land.IsManaland,
+			Occupancy:       land.Occupancy,
+			Status:          land.Status,
+			RAMTotal:        land.RAMTotal,
+			RAMAllocated:    land.RAMAllocated,
+			Trees:           processJSONToViews(land.Trees),
+			Treehouses:      processJSONToViews(land.Treehouses),
+			Nims:            processJSONToViews(land.Nims),
+			HiddenProcesses: land.HiddenProcesses,
+		}
+	}
+	return state
+}
+
+func processJSONToViews(processes []ProcessJSON) []ProcessView {
+	if processes == nil {
+		return nil
+	}
+	result := make([]ProcessView, len(processes))
+	for i, p := range processes {
+		result[i] = ProcessView{
+			ID:           p.ID,
+			Name:         p.Name,
+			Type:         p.Type,
+			RAMAllocated: p.RAMAllocated,
+			Progress:     p.Progress,
+			State:        p.State,
+			Subjects:     p.Subjects,
+			ParentID:     p.ParentID,
 		}
 	}
 	return result
@@ -132,3 +321,28 @@ func ViewStateToJSONBytes(state *ViewState) ([]byte, error) {
 	worldJSON := ViewStateToJSON(state)
 	return json.Marshal(worldJSON)
 }
+
+// DiffWorldJSON compares two worlds by land ID and returns the lands that were
+// added or changed in curr, and the IDs of lands that are no longer present.
+func DiffWorldJSON(prev, curr WorldJSON) (changed []LandJSON, removed []string) {
+	prevLands := make(map[string]LandJSON, len(prev.Lands))
+	for _, land := range prev.Lands {
+		prevLands[land.ID] = land
+	}
+
+	changed = []LandJSON{}
+	for _, land := range curr.Lands {
+		old, ok := prevLands[land.ID]
+		if !ok || !reflect.DeepEqual(old, land) {
+			changed = append(changed, land)
+		}
+		delete(prevLands, land.ID)
+	}
+
+	for _, land := range prev.Lands {
+		if _, ok := prevLands[land.ID]; ok {
+			removed = append(removed, land.ID)
+		}
+	}
+	return changed, removed
+}
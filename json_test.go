@@ -0,0 +1,67 @@
+package nimsforestviewer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSubjectEdgesSharedSubject(t *testing.T) {
+	lands := []LandView{
+		{ID: "land-1", Trees: []ProcessView{
+			{ID: "tree", Subjects: []string{"orders", "audit"}},
+		}},
+		{ID: "land-2", Nims: []ProcessView{
+			{ID: "nim-1", Subjects: []string{"orders"}},
+			{ID: "nim-2", Subjects: []string{"orders", "audit"}},
+			{ID: "nim-3", Subjects: []string{"orders"}},
+		}},
+	}
+	want := []EdgeJSON{
+		{Subject: "audit", From: "tree", FromLand: "land-1", To: "nim-2", ToLand: "land-2"},
+		{Subject: "orders", From: "tree", FromLand: "land-1", To: "nim-1", ToLand: "land-2"},
+		{Subject: "orders", From: "tree", FromLand: "land-1", To: "nim-2", ToLand: "land-2"},
+		{Subject: "orders", From: "tree", FromLand: "land-1", To: "nim-3", ToLand: "land-2"},
+	}
+	if got := subjectEdges(lands); !reflect.DeepEqual(got, want) {
+		t.Errorf("subjectEdges = %+v, want %+v", got, want)
+	}
+}
+
+func TestSubjectEdgesScaleLinearly(t *testing.T) {
+	// One subject shared by every process must not produce an edge per pair
+	const n = 1000
+	land := LandView{ID: "land"}
+	for i := 0; i < n; i++ {
+		land.Nims = append(land.Nims, ProcessView{ID: fmt.Sprintf("nim-%d", i), Subjects: []string{"broadcast"}})
+	}
+	if got := len(subjectEdges([]LandView{land})); got != n-1 {
+		t.Errorf("got %d edges for %d processes on one subject, want %d", got, n, n-1)
+	}
+}
+
+func TestDiffWorldJSON(t *testing.T) {
+	prev := WorldJSON{Lands: []LandJSON{
+		{ID: "same", Hostname: "a"},
+		{ID: "edited", Hostname: "b"},
+		{ID: "gone", Hostname: "c"},
+	}}
+	curr := WorldJSON{Lands: []LandJSON{
+		{ID: "new", Hostname: "d"},
+		{ID: "edited", Hostname: "b", Nims: []ProcessJSON{{ID: "n"}}},
+		{ID: "same", Hostname: "a"},
+	}}
+	changed, removed := DiffWorldJSON(prev, curr)
+	if want := []LandJSON{curr.Lands[0], curr.Lands[1]}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed = %+v, want %+v", changed, want)
+	}
+	if want := []string{"gone"}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("removed = %v, want %v", removed, want)
+	}
+
+	// Identical worlds diff to an empty, non-nil list so it encodes as []
+	changed, removed = DiffWorldJSON(curr, curr)
+	if changed == nil || len(changed) != 0 || removed != nil {
+		t.Errorf("diff of identical worlds = %v, %v; want [], nil", changed, removed)
+	}
+}
@@ -0,0 +1,244 @@
+package nimsforestviewer
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+)
+
+// LayoutFunc assigns grid positions to the lands of a state in place.
+type LayoutFunc func(state *ViewState)
+
+// PackGrid reassigns GridX/GridY so lands form a dense grid with cols columns,
+// filled left-to-right, top-to-bottom without holes. Lands keep their relative
+// order: they are packed in the row-major order of their current positions.
+// A cols value of zero or less uses a square grid.
+func PackGrid(state *ViewState, cols int) {
+	if state == nil || len(state.Lands) == 0 {
+		return
+	}
+	if cols <= 0 {
+		cols = squareGridSize(len(state.Lands))
+	}
+
+	positions := layoutGrid(state.Lands)
+	order := make([]int, len(state.Lands))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		pa, pb := positions[order[a]], positions[order[b]]
+		if pa.Y != pb.Y {
+			return pa.Y < pb.Y
+		}
+		return pa.X < pb.X
+	})
+
+	for slot, i := range order {
+		land := &state.Lands[i]
+		land.GridX = slot % cols
+		land.GridY = slot / cols
+		land.HasGridPosition = true
+	}
+}
+
+// PackedLayout returns a LayoutFunc that applies PackGrid with cols columns.
+func PackedLayout(cols int) LayoutFunc {
+	return func(state *ViewState) {
+		PackGrid(state, cols)
+	}
+}
+
+// OthersLandID is the ID of the tile AggregateLands folds excess lands into.
+const OthersLandID = "_others"
+
+// AggregateLands bounds the number of lands to max by folding the lowest-occupancy
+// lands into one tile with ID OthersLandID, whose RAM is their sum. Its processes
+// are dropped to keep payloads bounded. The remaining lands keep their order, and
+// Summary is left alone so it still describes the whole cluster.
+func AggregateLands(state *ViewState, max int) {
+	if state == nil || max <= 0 || len(state.Lands) <= max {
+		return
+	}
+
+	// Keep the max-1 busiest lands; the last slot is the others tile
+	order := make([]int, len(state.Lands))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return state.Lands[order[a]].Occupancy > state.Lands[order[b]].Occupancy
+	})
+	keep := make(map[int]bool, max-1)
+	for _, i := range order[:max-1] {
+		keep[i] = true
+	}
+
+	others := LandView{ID: OthersLandID}
+	var occupancySum float64
+	kept := make([]LandView, 0, max)
+	for i, land := range state.Lands {
+		if keep[i] {
+			kept = append(kept, land)
+			continue
+		}
+		others.RAMTotal += land.RAMTotal
+		others.RAMAllocated += land.RAMAllocated
+		occupancySum += land.Occupancy
+	}
+	folded := len(state.Lands) - len(kept)
+	others.Hostname = fmt.Sprintf("+%d others", folded)
+	if others.RAMTotal > 0 {
+		others.Occupancy = float64(others.RAMAllocated) / float64(others.RAMTotal)
+	} else {
+		others.Occupancy = occupancySum / float64(folded)
+	}
+	state.Lands = append(kept, others)
+}
+
+// MoreProcessesID is the ID of the process LimitProcesses folds a land's excess
+// processes into.
+const MoreProcessesID = "_more"
+
+// LimitProcesses bounds the processes on each land to max by folding the
+// smallest into one process with ID MoreProcessesID, named "+N more", whose RAM
+// is their sum. Processes are ranked by RAM, then by progress. The marker goes
+// into the type the most processes were folded from, and the land's
+// HiddenProcesses records how many it stands for. Kept processes keep their order.
+func LimitProcesses(state *ViewState, max int) {
+	if state == nil || max <= 0 {
+		return
+	}
+	for i := range state.Lands {
+		land := &state.Lands[i]
+		procs := land.AllProcesses()
+		if len(procs) <= max {
+			continue
+		}
+
+		order := make([]int, len(procs))
+		for j := range order {
+			order[j] = j
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			pa, pb := &procs[order[a]], &procs[order[b]]
+			if pa.RAMAllocated != pb.RAMAllocated {
+				return pa.RAMAllocated > pb.RAMAllocated
+			}
+			return pa.Progress > pb.Progress
+		})
+		keep := make(map[int]bool, max-1)
+		for _, j := range order[:max-1] {
+			keep[j] = true
+		}
+
+		// procs lists trees, then treehouses, then nims; offset tracks the slice boundaries
+		more := ProcessView{ID: MoreProcessesID}
+		offset, mostFolded := 0, 0
+		var target *[]ProcessView
+		slices := []*[]ProcessView{&land.Trees, &land.Treehouses, &land.Nims}
+		for k, procType := range []string{"tree", "treehouse", "nim"} {
+			slice := slices[k]
+			var kept []ProcessView
+			folded := 0
+			for j, proc := range *slice {
+				if keep[offset+j] {
+					kept = append(kept, proc)
+					continue
+				}
+				more.RAMAllocated += proc.RAMAllocated
+				folded++
+			}
+			offset += len(*slice)
+			*slice = kept
+			if folded > mostFolded {
+				mostFolded, target, more.Type = folded, slice, procType
+			}
+		}
+		land.HiddenProcesses = len(procs) - (max - 1)
+		more.Name = fmt.Sprintf("+%d more", land.HiddenProcesses)
+		*target = append(*target, more)
+	}
+}
+
+// SortLands orders lands deterministically, so a provider returning them in map
+// order doesn't reshuffle the grid and API lists between updates. Positioned
+// lands come first in row-major grid order, then the rest by hostname and ID.
+// Processes within each land are sorted by ID.
+func SortLands(state *ViewState) {
+	if state == nil {
+		return
+	}
+	sort.SliceStable(state.Lands, func(i, j int) bool {
+		a, b := &state.Lands[i], &state.Lands[j]
+		if a.Positioned() != b.Positioned() {
+			return a.Positioned()
+		}
+		if a.Positioned() {
+			if a.GridY != b.GridY {
+				return a.GridY < b.GridY
+			}
+			if a.GridX != b.GridX {
+				return a.GridX < b.GridX
+			}
+		}
+		if a.Hostname != b.Hostname {
+			return a.Hostname < b.Hostname
+		}
+		return a.ID < b.ID
+	})
+	for i := range state.Lands {
+		land := &state.Lands[i]
+		sortProcesses(land.Trees)
+		sortProcesses(land.Treehouses)
+		sortProcesses(land.Nims)
+	}
+}
+
+func sortProcesses(processes []ProcessView) {
+	sort.SliceStable(processes, func(i, j int) bool {
+		return processes[i].ID < processes[j].ID
+	})
+}
+
+// layoutGrid returns the grid position of every land. Positioned lands keep
+// their coordinates; the others fill the free cells of a square grid in
+// row-major order, so they never land on top of a positioned land.
+func layoutGrid(lands []LandView) []image.Point {
+	positions := make([]image.Point, len(lands))
+	occupied := make(map[image.Point]bool, len(lands))
+	for i := range lands {
+		if lands[i].Positioned() {
+			positions[i] = image.Pt(lands[i].GridX, lands[i].GridY)
+			occupied[positions[i]] = true
+		}
+	}
+
+	gridSize := squareGridSize(len(lands))
+	next := 0
+	for i := range lands {
+		if lands[i].Positioned() {
+			continue
+		}
+		for {
+			p := image.Pt(next%gridSize, next/gridSize)
+			next++
+			if !occupied[p] {
+				positions[i] = p
+				occupied[p] = true
+				break
+			}
+		}
+	}
+	return positions
+}
+
+// squareGridSize returns the column count of the smallest square grid holding n lands.
+func squareGridSize(n int) int {
+	size := int(math.Ceil(math.Sqrt(float64(n))))
+	if size < 1 {
+		size = 1
+	}
+	return size
+}
@@ -0,0 +1,175 @@
+package nimsforestviewer
+
+import (
+	"image"
+	"reflect"
+	"testing"
+)
+
+func TestLayoutGridKeepsExplicitZeroPositions(t *testing.T) {
+	// Both lands intend column 0: a at the origin, b one row down
+	lands := []LandView{
+		{ID: "a", GridX: 0, GridY: 0, HasGridPosition: true},
+		{ID: "b", GridX: 0, GridY: 1},
+	}
+	got := layoutGrid(lands)
+	want := []image.Point{{0, 0}, {0, 1}}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("land %s at %v, want %v", lands[i].ID, got[i], want[i])
+		}
+	}
+
+	world := ViewStateToJSON(&ViewState{Lands: lands})
+	for i, land := range world.Lands {
+		if p := image.Pt(land.GridX, land.GridY); p != want[i] {
+			t.Errorf("JSON land %s at %v, want %v", land.ID, p, want[i])
+		}
+	}
+}
+
+func TestLayoutGridPlacesAroundExplicitOrigin(t *testing.T) {
+	// The unpositioned land must not be stacked on the one placed at (0, 0)
+	lands := []LandView{
+		{ID: "auto"},
+		{ID: "origin", HasGridPosition: true},
+	}
+	got := layoutGrid(lands)
+	if got[1] != image.Pt(0, 0) {
+		t.Errorf("positioned land moved to %v", got[1])
+	}
+	if got[0] == got[1] {
+		t.Errorf("auto-placed land shares %v with the positioned land", got[0])
+	}
+	if got[0] != image.Pt(1, 0) {
+		t.Errorf("auto-placed land at %v, want the next free cell (1,0)", got[0])
+	}
+}
+
+// processIDs returns the IDs of procs, or nil if there are none.
+func processIDs(procs []ProcessView) []string {
+	var ids []string
+	for _, p := range procs {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+func TestLimitProcesses(t *testing.T) {
+	// Ranked by RAM: t1 (10), n1 (8), th1 (5), n3 (3), n2 (2), t2 (1)
+	land := func() LandView {
+		return LandView{
+			ID:         "land",
+			Trees:      []ProcessView{{ID: "t1", RAMAllocated: 10}, {ID: "t2", RAMAllocated: 1}},
+			Treehouses: []ProcessView{{ID: "th1", RAMAllocated: 5}},
+			Nims:       []ProcessView{{ID: "n1", RAMAllocated: 8}, {ID: "n2", RAMAllocated: 2}, {ID: "n3", RAMAllocated: 3}},
+		}
+	}
+	tests := []struct {
+		name                    string
+		max                     int
+		trees, treehouses, nims []string
+		hidden                  int
+		more                    ProcessView // Zero when nothing is folded
+	}{
+		{
+			name:       "zero limit",
+			max:        0,
+			trees:      []string{"t1", "t2"},
+			treehouses: []string{"th1"},
+			nims:       []string{"n1", "n2", "n3"},
+		},
+		{
+			name:       "limit equals count",
+			max:        6,
+			trees:      []string{"t1", "t2"},
+			treehouses: []string{"th1"},
+			nims:       []string{"n1", "n2", "n3"},
+		},
+		{
+			// Folds one tree, one treehouse and two nims, so the marker is a nim
+			name:   "mixed-type overflow",
+			max:    3,
+			trees:  []string{"t1"},
+			nims:   []string{"n1", MoreProcessesID},
+			hidden: 4,
+			more:   ProcessView{ID: MoreProcessesID, Name: "+4 more", Type: "nim", RAMAllocated: 11},
+		},
+		{
+			name:   "limit one",
+			max:    1,
+			nims:   []string{MoreProcessesID},
+			hidden: 6,
+			more:   ProcessView{ID: MoreProcessesID, Name: "+6 more", Type: "nim", RAMAllocated: 29},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := &ViewState{Lands: []LandView{land()}}
+			LimitProcesses(state, tt.max)
+			got := state.Lands[0]
+			if ids := processIDs(got.Trees); !reflect.DeepEqual(ids, tt.trees) {
+				t.Errorf("trees = %v, want %v", ids, tt.trees)
+			}
+			if ids := processIDs(got.Treehouses); !reflect.DeepEqual(ids, tt.treehouses) {
+				t.Errorf("treehouses = %v, want %v", ids, tt.treehouses)
+			}
+			if ids := processIDs(got.Nims); !reflect.DeepEqual(ids, tt.nims) {
+				t.Errorf("nims = %v, want %v", ids, tt.nims)
+			}
+			if got.HiddenProcesses != tt.hidden {
+				t.Errorf("HiddenProcesses = %d, want %d", got.HiddenProcesses, tt.hidden)
+			}
+			if tt.more.ID != "" {
+				if more := got.Nims[len(got.Nims)-1]; !reflect.DeepEqual(more, tt.more) {
+					t.Errorf("marker = %+v, want %+v", more, tt.more)
+				}
+			}
+		})
+	}
+}
+
+func TestAggregateLands(t *testing.T) {
+	lands := func() []LandView {
+		return []LandView{
+			{ID: "a", Occupancy: 0.9, RAMTotal: 10, RAMAllocated: 9},
+			{ID: "b", Occupancy: 0.1, RAMTotal: 10, RAMAllocated: 1, Nims: []ProcessView{{ID: "n"}}},
+			{ID: "c", Occupancy: 0.5, RAMTotal: 10, RAMAllocated: 5},
+			{ID: "d", Occupancy: 0.2, RAMTotal: 30, RAMAllocated: 6},
+		}
+	}
+	tests := []struct {
+		name   string
+		max    int
+		ids    []string
+		others LandView // Zero when nothing is folded
+	}{
+		{name: "zero limit", max: 0, ids: []string{"a", "b", "c", "d"}},
+		{name: "limit equals count", max: 4, ids: []string{"a", "b", "c", "d"}},
+		{
+			// b and d are the least occupied; the others tile drops b's processes
+			name:   "overflow",
+			max:    3,
+			ids:    []string{"a", "c", OthersLandID},
+			others: LandView{ID: OthersLandID, Hostname: "+2 others", RAMTotal: 40, RAMAllocated: 7, Occupancy: 7.0 / 40},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := &ViewState{Lands: lands()}
+			AggregateLands(state, tt.max)
+			var ids []string
+			for _, land := range state.Lands {
+				ids = append(ids, land.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.ids) {
+				t.Fatalf("lands = %v, want %v", ids, tt.ids)
+			}
+			if tt.others.ID != "" {
+				if others := state.Lands[len(state.Lands)-1]; !reflect.DeepEqual(others, tt.others) {
+					t.Errorf("others tile = %+v, want %+v", others, tt.others)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package nimsforestviewer
+
+import (
+	"log"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+)
+
+// Logger receives diagnostic messages from the Viewer and its targets.
+// kv holds alternating keys and values, as in log/slog.
+// Implementations must be safe for concurrent use.
+type Logger interface {
+	Debug(msg string, kv ...any)
+	Info(msg string, kv ...any)
+	Warn(msg string, kv ...any)
+	Error(msg string, kv ...any)
+}
+
+// NopLogger discards everything. It is the default.
+var NopLogger Logger = nopLogger{}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...any) {}
+func (nopLogger) Info(string, ...any)  {}
+func (nopLogger) Warn(string, ...any)  {}
+func (nopLogger) Error(string, ...any) {}
+
+// NewSlogLogger adapts a slog.Logger. A nil l uses slog.Default().
+func NewSlogLogger(l *slog.Logger) Logger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return slogLogger{l}
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (s slogLogger) Debug(msg string, kv ...any) { s.l.Debug(msg, kv...) }
+func (s slogLogger) Info(msg string, kv ...any)  { s.l.Info(msg, kv...) }
+func (s slogLogger) Warn(msg string, kv ...any)  { s.l.Warn(msg, kv...) }
+func (s slogLogger) Error(msg string, kv ...any) { s.l.Error(msg, kv...) }
+
+// LoggingTarget is implemented by targets that report their own activity,
+// such as servers starting or frames sent to a TV.
+// A Viewer created with WithLogger passes its logger to every LoggingTarget it is given.
+type LoggingTarget interface {
+	Target
+	SetLogger(l Logger)
+}
+
+// loggable holds a target's logger. Targets embed it to implement LoggingTarget.
+type loggable struct {
+	logger atomic.Pointer[Logger]
+}
+
+// SetLogger sets where the target logs. A nil l discards messages.
+func (t *loggable) SetLogger(l Logger) {
+	if l == nil {
+		l = NopLogger
+	}
+	t.logger.Store(&l)
+}
+
+// log returns the target's logger, or NopLogger if none was set.
+func (t *loggable) log() Logger {
+	if l := t.logger.Load(); l != nil {
+		return *l
+	}
+	return NopLogger
+}
+
+// httpErrorLog returns a log.Logger for http.Server.ErrorLog that forwards to l.
+func httpErrorLog(l Logger, server string) *log.Logger {
+	return log.New(logWriter{l: l, server: server}, "", 0)
+}
+
+type logWriter struct {
+	l      Logger
+	server string
+}
+
+func (w logWriter) Write(p []byte) (int, error) {
+	w.l.Error("http server error", "server", w.server, "error", strings.TrimSpace(string(p)))
+	return len(p), nil
+}
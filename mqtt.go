@@ -0,0 +1,303 @@
+package nimsforestviewer
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// MQTT 3.1.1 control packet types, already shifted into the fixed header's upper nibble.
+const (
+	mqttConnect    = 0x10
+	mqttConnack    = 0x20
+	mqttPublish    = 0x30
+	mqttPuback     = 0x40
+	mqttPingreq    = 0xC0
+	mqttPingresp   = 0xD0
+	mqttDisconnect = 0xE0
+)
+
+// mqttConnackErrors maps CONNACK return codes to their meaning.
+var mqttConnackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// mqttConfig holds the connection settings of an mqttClient.
+type mqttConfig struct {
+	clientID  string
+	username  string
+	password  string
+	keepAlive time.Duration
+	tlsConfig *tls.Config // Used for ssl://, tls:// and mqtts:// brokers
+}
+
+// mqttClient is a minimal MQTT 3.1.1 client that can publish at QoS 0 or 1.
+type mqttClient struct {
+	conn    net.Conn
+	writeMu sync.Mutex
+
+	mu     sync.Mutex
+	nextID uint16
+	acks   map[uint16]chan struct{}
+
+	done chan struct{}
+	err  error // Error that ended the connection; valid after done is closed
+}
+
+// dialMQTT connects to the broker at rawURL and completes the CONNECT handshake.
+// Supported schemes are tcp and mqtt, and ssl, tls and mqtts for TLS.
+func dialMQTT(ctx context.Context, rawURL string, cfg mqttConfig) (*mqttClient, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse broker URL: %w", err)
+	}
+
+	var useTLS bool
+	port := "1883"
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "ssl", "tls", "mqtts":
+		useTLS, port = true, "8883"
+	default:
+		return nil, fmt.Errorf("unsupported broker scheme %q", u.Scheme)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	var conn net.Conn
+	if useTLS {
+		tlsCfg := cfg.tlsConfig
+		if tlsCfg == nil {
+			tlsCfg = &tls.Config{ServerName: u.Hostname()}
+		}
+		conn, err = (&tls.Dialer{Config: tlsCfg}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &mqttClient{
+		conn: conn,
+		acks: make(map[uint16]chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	reader := bufio.NewReader(conn)
+	if err := c.handshake(reader, cfg); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	go c.readLoop(reader)
+	if cfg.keepAlive > 0 {
+		go c.pingLoop(cfg.keepAlive)
+	}
+	return c, nil
+}
+
+// handshake sends CONNECT and waits for a successful CONNACK.
+func (c *mqttClient) handshake(r *bufio.Reader, cfg mqttConfig) error {
+	flags := byte(0x02) // Clean session
+	if cfg.username != "" {
+		flags |= 0x80
+	}
+	if cfg.password != "" {
+		flags |= 0x40
+	}
+
+	body := appendMQTTString(nil, "MQTT")
+	body = append(body, 4, flags) // Protocol level 4 is MQTT 3.1.1
+	body = binary.BigEndian.AppendUint16(body, uint16(cfg.keepAlive/time.Second))
+	body = appendMQTTString(body, cfg.clientID)
+	if cfg.username != "" {
+		body = appendMQTTString(body, cfg.username)
+	}
+	if cfg.password != "" {
+		body = appendMQTTString(body, cfg.password)
+	}
+	if err := c.writePacket(mqttConnect, body); err != nil {
+		return fmt.Errorf("send CONNECT: %w", err)
+	}
+
+	header, resp, err := readMQTTPacket(r)
+	if err != nil {
+		return fmt.Errorf("read CONNACK: %w", err)
+	}
+	if header&0xF0 != mqttConnack || len(resp) != 2 {
+		return fmt.Errorf("unexpected packet 0x%02x during handshake", header)
+	}
+	if code := resp[1]; code != 0 {
+		if msg, ok := mqttConnackErrors[code]; ok {
+			return fmt.Errorf("connection refused: %s", msg)
+		}
+		return fmt.Errorf("connection refused: code %d", code)
+	}
+	return nil
+}
+
+// publish sends payload to topic. At QoS 1 it waits for the broker's PUBACK.
+func (c *mqttClient) publish(ctx context.Context, topic string, payload []byte, qos byte, retain bool) error {
+	header := byte(mqttPublish) | qos<<1
+	if retain {
+		header |= 0x01
+	}
+
+	body := appendMQTTString(nil, topic)
+	var ack chan struct{}
+	var id uint16
+	if qos > 0 {
+		id, ack = c.registerAck()
+		defer c.releaseAck(id)
+		body = binary.BigEndian.AppendUint16(body, id)
+	}
+	body = append(body, payload...)
+
+	if err := c.writePacket(header, body); err != nil {
+		return err
+	}
+	if ack == nil {
+		return nil
+	}
+
+	select {
+	case <-ack:
+		return nil
+	case <-c.done:
+		return fmt.Errorf("connection closed: %w", c.err)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// disconnect sends DISCONNECT and closes the connection.
+func (c *mqttClient) disconnect() error {
+	err := c.writePacket(mqttDisconnect, nil)
+	c.conn.Close()
+	<-c.done
+	return err
+}
+
+// alive reports whether the connection is still usable.
+func (c *mqttClient) alive() bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+		return true
+	}
+}
+
+func (c *mqttClient) registerAck() (uint16, chan struct{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Packet identifiers must be non-zero
+	c.nextID++
+	if c.nextID == 0 {
+		c.nextID = 1
+	}
+	ch := make(chan struct{})
+	c.acks[c.nextID] = ch
+	return c.nextID, ch
+}
+
+func (c *mqttClient) releaseAck(id uint16) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.acks, id)
+}
+
+// writePacket writes a control packet with the given fixed header byte and body.
+func (c *mqttClient) writePacket(header byte, body []byte) error {
+	pkt := binary.AppendUvarint([]byte{header}, uint64(len(body))) // Remaining length uses the same 7-bit encoding
+	pkt = append(pkt, body...)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := c.conn.Write(pkt)
+	return err
+}
+
+// readLoop handles acknowledgements until the connection fails.
+func (c *mqttClient) readLoop(r *bufio.Reader) {
+	defer close(c.done)
+	for {
+		header, body, err := readMQTTPacket(r)
+		if err != nil {
+			c.err = err
+			return
+		}
+		if header&0xF0 == mqttPuback && len(body) == 2 {
+			id := binary.BigEndian.Uint16(body)
+			c.mu.Lock()
+			if ch, ok := c.acks[id]; ok {
+				close(ch)
+				delete(c.acks, id)
+			}
+			c.mu.Unlock()
+		}
+		// PINGRESP and anything else needs no action
+	}
+}
+
+// pingLoop keeps the connection alive while no other packets are sent.
+func (c *mqttClient) pingLoop(keepAlive time.Duration) {
+	ticker := time.NewTicker(keepAlive * 3 / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.writePacket(mqttPingreq, nil); err != nil {
+				c.conn.Close()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// readMQTTPacket reads one control packet.
+func readMQTTPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if size > 268435455 {
+		return 0, nil, errors.New("remaining length exceeds MQTT limit")
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
+
+func appendMQTTString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
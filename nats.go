@@ -0,0 +1,268 @@
+package nimsforestviewer
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// natsMaxPayload bounds a single message, matching the NATS server's default max_payload.
+const natsMaxPayload = 1 << 20
+
+// natsConfig holds the connection settings of a natsClient.
+type natsConfig struct {
+	username     string
+	password     string
+	token        string
+	pingInterval time.Duration
+	tlsConfig    *tls.Config // Used for tls:// servers and servers that require TLS
+}
+
+// natsInfo is the part of the server's INFO message the client needs.
+type natsInfo struct {
+	TLSRequired bool `json:"tls_required"`
+	MaxPayload  int  `json:"max_payload"`
+}
+
+// natsClient is a minimal NATS client that subscribes to a single subject.
+type natsClient struct {
+	conn    net.Conn
+	writeMu sync.Mutex
+
+	done chan struct{}
+	err  error // Error that ended the connection; valid after done is closed
+}
+
+// dialNATS connects to the server at rawURL, subscribes to subject and delivers
+// each message payload to onMsg from the read loop. Supported schemes are nats
+// and tls.
+func dialNATS(ctx context.Context, rawURL, subject string, cfg natsConfig, onMsg func([]byte)) (*natsClient, error) {
+	u, addr, err := parseNATSURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	useTLS := u.Scheme == "tls"
+	if u.User != nil && cfg.username == "" && cfg.token == "" {
+		cfg.username = u.User.Username()
+		cfg.password, _ = u.User.Password()
+	}
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// The server speaks first; TLS, when required, starts after its INFO
+	reader := bufio.NewReader(conn)
+	info, err := readNATSInfo(reader)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if useTLS || info.TLSRequired {
+		tlsCfg := cfg.tlsConfig
+		if tlsCfg == nil {
+			tlsCfg = &tls.Config{ServerName: u.Hostname()}
+		}
+		tlsConn := tls.Client(conn, tlsCfg)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("TLS handshake: %w", err)
+		}
+		conn = tlsConn
+		reader = bufio.NewReader(conn)
+	}
+
+	c := &natsClient{conn: conn, done: make(chan struct{})}
+	if err := c.handshake(reader, subject, cfg); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	maxPayload := info.MaxPayload
+	if maxPayload <= 0 {
+		maxPayload = natsMaxPayload
+	}
+	go c.readLoop(reader, maxPayload, cfg.pingInterval, onMsg)
+	if cfg.pingInterval > 0 {
+		go c.pingLoop(cfg.pingInterval)
+	}
+	return c, nil
+}
+
+// parseNATSURL parses a nats:// or tls:// server URL and returns it with the
+// address to dial, which defaults to port 4222.
+func parseNATSURL(rawURL string) (*url.URL, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("parse server URL: %w", err)
+	}
+	if u.Scheme != "nats" && u.Scheme != "tls" {
+		return nil, "", fmt.Errorf("unsupported server scheme %q", u.Scheme)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	return u, addr, nil
+}
+
+// readNATSInfo reads the INFO line the server sends on connect.
+func readNATSInfo(r *bufio.Reader) (natsInfo, error) {
+	var info natsInfo
+	line, err := readNATSLine(r)
+	if err != nil {
+		return info, fmt.Errorf("read INFO: %w", err)
+	}
+	payload, ok := strings.CutPrefix(line, "INFO ")
+	if !ok {
+		return info, fmt.Errorf("expected INFO, got %q", line)
+	}
+	if err := json.Unmarshal([]byte(payload), &info); err != nil {
+		return info, fmt.Errorf("parse INFO: %w", err)
+	}
+	return info, nil
+}
+
+// handshake sends CONNECT and SUB, then waits for the PONG that answers its PING,
+// which confirms the server accepted both.
+func (c *natsClient) handshake(r *bufio.Reader, subject string, cfg natsConfig) error {
+	connect := map[string]any{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "nimsforestviewer",
+		"lang":     "go",
+		"protocol": 1,
+	}
+	if cfg.token != "" {
+		connect["auth_token"] = cfg.token
+	}
+	if cfg.username != "" {
+		connect["user"] = cfg.username
+		connect["pass"] = cfg.password
+	}
+	opts, err := json.Marshal(connect)
+	if err != nil {
+		return err
+	}
+	if err := c.write(fmt.Sprintf("CONNECT %s\r\nSUB %s 1\r\nPING\r\n", opts, subject)); err != nil {
+		return fmt.Errorf("send CONNECT: %w", err)
+	}
+
+	for {
+		line, err := readNATSLine(r)
+		if err != nil {
+			return fmt.Errorf("read handshake reply: %w", err)
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("server error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+		// +OK and repeated INFO need no action
+	}
+}
+
+// close ends the connection.
+func (c *natsClient) close() error {
+	return c.conn.Close()
+}
+
+func (c *natsClient) write(s string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err := io.WriteString(c.conn, s)
+	return err
+}
+
+// readLoop delivers messages and answers server pings until the connection fails.
+// Without any traffic for two ping intervals the connection is considered dead.
+func (c *natsClient) readLoop(r *bufio.Reader, maxPayload int, pingInterval time.Duration, onMsg func([]byte)) {
+	defer close(c.done)
+	for {
+		if pingInterval > 0 {
+			c.conn.SetReadDeadline(time.Now().Add(2 * pingInterval))
+		}
+		line, err := readNATSLine(r)
+		if err != nil {
+			c.err = err
+			c.conn.Close()
+			return
+		}
+
+		switch {
+		case strings.HasPrefix(line, "MSG "):
+			// MSG <subject> <sid> [reply-to] <#bytes>
+			fields := strings.Fields(line)
+			size, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil || size < 0 || size > maxPayload {
+				c.err = fmt.Errorf("bad MSG line %q", line)
+				c.conn.Close()
+				return
+			}
+			payload := make([]byte, size+2) // Payload and its trailing CRLF
+			if _, err := io.ReadFull(r, payload); err != nil {
+				c.err = err
+				c.conn.Close()
+				return
+			}
+			onMsg(payload[:size])
+		case line == "PING":
+			if err := c.write("PONG\r\n"); err != nil {
+				c.err = err
+				c.conn.Close()
+				return
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			c.err = fmt.Errorf("server error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+			c.conn.Close()
+			return
+		}
+		// PONG, +OK and INFO need no action
+	}
+}
+
+// pingLoop pings the server so a silently dropped connection is noticed.
+func (c *natsClient) pingLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.write("PING\r\n"); err != nil {
+				c.conn.Close()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// readNATSLine reads one CRLF-terminated protocol line without its terminator.
+func readNATSLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return "", errors.New("empty protocol line")
+	}
+	return line, nil
+}
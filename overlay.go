@@ -0,0 +1,261 @@
+package nimsforestviewer
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"sync/atomic"
+	"time"
+)
+
+// Corner is the corner of the frame an overlay is anchored to.
+type Corner int
+
+// Overlay positions.
+const (
+	CornerTopLeft Corner = iota
+	CornerTopRight
+	CornerBottomLeft
+	CornerBottomRight
+)
+
+// overlay is a text banner or an image drawn onto frames.
+type overlay struct {
+	text   string
+	img    image.Image
+	corner Corner
+}
+
+// overlays draws text, images and status information onto frames just before
+// they are encoded. Image targets embed it.
+type overlays struct {
+	items     []overlay
+	timestamp bool
+	updated   atomic.Int64 // UnixNano of the last state the target received
+}
+
+// addText adds a text banner at corner.
+func (o *overlays) addText(text string, corner Corner) {
+	o.items = append(o.items, overlay{text: text, corner: corner})
+}
+
+// addImage adds an image, such as a logo, at corner.
+func (o *overlays) addImage(img image.Image, corner Corner) {
+	if img != nil {
+		o.items = append(o.items, overlay{img: img, corner: corner})
+	}
+}
+
+// markUpdated records that the target received new state, for the "updated" age.
+func (o *overlays) markUpdated() {
+	o.updated.Store(time.Now().UnixNano())
+}
+
+// drawOverlays draws the enabled overlays onto img as of now and returns the
+// result, which may be img itself. Overlays sharing a corner are stacked in the
+// order they were added, with the timestamp last.
+func (o *overlays) drawOverlays(img image.Image, now time.Time) image.Image {
+	if len(o.items) == 0 && !o.timestamp {
+		return img
+	}
+	rgba := ensureRGBA(img)
+	scale := overlayScale(rgba.Bounds())
+	margin := 2 * scale
+	var stacked [4]int // Height used so far in each corner
+
+	place := func(size image.Point, corner Corner) image.Rectangle {
+		b := rgba.Bounds()
+		p := image.Pt(b.Min.X+margin, b.Min.Y+margin+stacked[corner])
+		if corner == CornerTopRight || corner == CornerBottomRight {
+			p.X = b.Max.X - margin - size.X
+		}
+		if corner == CornerBottomLeft || corner == CornerBottomRight {
+			p.Y = b.Max.Y - margin - stacked[corner] - size.Y
+		}
+		stacked[corner] += size.Y + margin
+		return image.Rectangle{Min: p, Max: p.Add(size)}
+	}
+	drawBanner := func(text string, corner Corner) {
+		w, h := textSize(text, scale)
+		box := place(image.Pt(w+2*margin, h+2*margin), corner)
+		drawPanel(rgba, box)
+		drawText(rgba, box.Min.X+margin, box.Min.Y+margin, text, scale, color.RGBA{255, 255, 255, 255})
+	}
+
+	for _, item := range o.items {
+		if item.img != nil {
+			r := place(item.img.Bounds().Size(), item.corner)
+			draw.Draw(rgba, r, item.img, item.img.Bounds().Min, draw.Over)
+			continue
+		}
+		drawBanner(item.text, item.corner)
+	}
+	if o.timestamp {
+		drawBanner(timestampText(now, o.updated.Load()), CornerBottomRight)
+	}
+	return rgba
+}
+
+// timestampText formats the time, followed by how long ago the state was updated
+// once that is a second or more. A frozen clock or a growing age tells operators
+// the feed has stalled.
+func timestampText(now time.Time, updated int64) string {
+	text := now.Format("2006-01-02 15:04:05")
+	if updated != 0 {
+		if age := now.Sub(time.Unix(0, updated)).Truncate(time.Second); age >= time.Second {
+			text += fmt.Sprintf("  updated %s ago", age)
+		}
+	}
+	return text
+}
+
+// overlayScale picks a font scale that stays legible across a room:
+// 4 for a 1080-line frame, and never less than 1.
+func overlayScale(b image.Rectangle) int {
+	return max(1, b.Dy()/270)
+}
+
+// drawPanel darkens r so text on it reads against any background.
+func drawPanel(dst *image.RGBA, r image.Rectangle) {
+	draw.Draw(dst, r.Intersect(dst.Bounds()), image.NewUniform(color.RGBA{0, 0, 0, 160}), image.Point{}, draw.Over)
+}
+
+// Glyphs are 5x7 pixels with one pixel of spacing, scaled up by an integer factor.
+const (
+	glyphWidth   = 5
+	glyphHeight  = 7
+	glyphAdvance = glyphWidth + 1
+)
+
+// textSize returns the size of text drawn by drawText at scale.
+func textSize(text string, scale int) (w, h int) {
+	n := len([]rune(text))
+	if n == 0 {
+		return 0, glyphHeight * scale
+	}
+	return (n*glyphAdvance - 1) * scale, glyphHeight * scale
+}
+
+// drawText draws text with its top-left corner at (x, y) using the built-in
+// bitmap font, so no fonts need to be installed. Characters outside printable
+// ASCII are drawn as '?'.
+func drawText(dst *image.RGBA, x, y int, text string, scale int, c color.RGBA) {
+	fill := image.NewUniform(c)
+	for _, r := range text {
+		if r < ' ' || r > '~' {
+			r = '?'
+		}
+		glyph := bitmapFont[r-' ']
+		for row, bits := range glyph {
+			for col := 0; col < glyphWidth; col++ {
+				if bits&(1<<(glyphWidth-1-col)) == 0 {
+					continue
+				}
+				px := image.Rect(x+col*scale, y+row*scale, x+(col+1)*scale, y+(row+1)*scale)
+				draw.Draw(dst, px.Intersect(dst.Bounds()), fill, image.Point{}, draw.Over)
+			}
+		}
+		x += glyphAdvance * scale
+	}
+}
+
+// bitmapFont holds the printable ASCII characters from ' ' to '~', one row of
+// five pixels per byte with the leftmost pixel in bit 4.
+var bitmapFont = [...][glyphHeight]uint8{
+	{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, // ' '
+	{0x04, 0x04, 0x04, 0x04, 0x04, 0x00, 0x04}, // '!'
+	{0x0a, 0x0a, 0x00, 0x00, 0x00, 0x00, 0x00}, // '"'
+	{0x0a, 0x0a, 0x1f, 0x0a, 0x1f, 0x0a, 0x0a}, // '#'
+	{0x04, 0x0f, 0x14, 0x0e, 0x05, 0x1e, 0x04}, // '$'
+	{0x18, 0x19, 0x02, 0x04, 0x08, 0x13, 0x03}, // '%'
+	{0x0c, 0x12, 0x14, 0x08, 0x15, 0x12, 0x0d}, // '&'
+	{0x04, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00}, // '\''
+	{0x02, 0x04, 0x08, 0x08, 0x08, 0x04, 0x02}, // '('
+	{0x08, 0x04, 0x02, 0x02, 0x02, 0x04, 0x08}, // ')'
+	{0x00, 0x04, 0x15, 0x0e, 0x15, 0x04, 0x00}, // '*'
+	{0x00, 0x04, 0x04, 0x1f, 0x04, 0x04, 0x00}, // '+'
+	{0x00, 0x00, 0x00, 0x00, 0x0c, 0x04, 0x08}, // ','
+	{0x00, 0x00, 0x00, 0x1f, 0x00, 0x00, 0x00}, // '-'
+	{0x00, 0x00, 0x00, 0x00, 0x00, 0x0c, 0x0c}, // '.'
+	{0x00, 0x01, 0x02, 0x04, 0x08, 0x10, 0x00}, // '/'
+	{0x0e, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0e}, // '0'
+	{0x04, 0x0c, 0x04, 0x04, 0x04, 0x04, 0x0e}, // '1'
+	{0x0e, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1f}, // '2'
+	{0x1f, 0x02, 0x04, 0x02, 0x01, 0x11, 0x0e}, // '3'
+	{0x02, 0x06, 0x0a, 0x12, 0x1f, 0x02, 0x02}, // '4'
+	{0x1f, 0x10, 0x1e, 0x01, 0x01, 0x11, 0x0e}, // '5'
+	{0x06, 0x08, 0x10, 0x1e, 0x11, 0x11, 0x0e}, // '6'
+	{0x1f, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08}, // '7'
+	{0x0e, 0x11, 0x11, 0x0e, 0x11, 0x11, 0x0e}, // '8'
+	{0x0e, 0x11, 0x11, 0x0f, 0x01, 0x02, 0x0c}, // '9'
+	{0x00, 0x0c, 0x0c, 0x00, 0x0c, 0x0c, 0x00}, // ':'
+	{0x00, 0x0c, 0x0c, 0x00, 0x0c, 0x04, 0x08}, // ';'
+	{0x02, 0x04, 0x08, 0x10, 0x08, 0x04, 0x02}, // '<'
+	{0x00, 0x00, 0x1f, 0x00, 0x1f, 0x00, 0x00}, // '='
+	{0x08, 0x04, 0x02, 0x01, 0x02, 0x04, 0x08}, // '>'
+	{0x0e, 0x11, 0x01, 0x02, 0x04, 0x00, 0x04}, // '?'
+	{0x0e, 0x11, 0x01, 0x0d, 0x15, 0x15, 0x0e}, // '@'
+	{0x0e, 0x11, 0x11, 0x1f, 0x11, 0x11, 0x11}, // 'A'
+	{0x1e, 0x11, 0x11, 0x1e, 0x11, 0x11, 0x1e}, // 'B'
+	{0x0e, 0x11, 0x10, 0x10, 0x10, 0x11, 0x0e}, // 'C'
+	{0x1c, 0x12, 0x11, 0x11, 0x11, 0x12, 0x1c}, // 'D'
+	{0x1f, 0x10, 0x10, 0x1e, 0x10, 0x10, 0x1f}, // 'E'
+	{0x1f, 0x10, 0x10, 0x1e, 0x10, 0x10, 0x10}, // 'F'
+	{0x0e, 0x11, 0x10, 0x17, 0x11, 0x11, 0x0f}, // 'G'
+	{0x11, 0x11, 0x11, 0x1f, 0x11, 0x11, 0x11}, // 'H'
+	{0x0e, 0x04, 0x04, 0x04, 0x04, 0x04, 0x0e}, // 'I'
+	{0x07, 0x02, 0x02, 0x02, 0x02, 0x12, 0x0c}, // 'J'
+	{0x11, 0x12, 0x14, 0x18, 0x14, 0x12, 0x11}, // 'K'
+	{0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x1f}, // 'L'
+	{0x11, 0x1b, 0x15, 0x15, 0x11, 0x11, 0x11}, // 'M'
+	{0x11, 0x11, 0x19, 0x15, 0x13, 0x11, 0x11}, // 'N'
+	{0x0e, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0e}, // 'O'
+	{0x1e, 0x11, 0x11, 0x1e, 0x10, 0x10, 0x10}, // 'P'
+	{0x0e, 0x11, 0x11, 0x11, 0x15, 0x12, 0x0d}, // 'Q'
+	{0x1e, 0x11, 0x11, 0x1e, 0x14, 0x12, 0x11}, // 'R'
+	{0x0f, 0x10, 0x10, 0x0e, 0x01, 0x01, 0x1e}, // 'S'
+	{0x1f, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04}, // 'T'
+	{0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0e}, // 'U'
+	{0x11, 0x11, 0x11, 0x11, 0x11, 0x0a, 0x04}, // 'V'
+	{0x11, 0x11, 0x11, 0x15, 0x15, 0x15, 0x0a}, // 'W'
+	{0x11, 0x11, 0x0a, 0x04, 0x0a, 0x11, 0x11}, // 'X'
+	{0x11, 0x11, 0x0a, 0x04, 0x04, 0x04, 0x04}, // 'Y'
+	{0x1f, 0x01, 0x02, 0x04, 0x08, 0x10, 0x1f}, // 'Z'
+	{0x0e, 0x08, 0x08, 0x08, 0x08, 0x08, 0x0e}, // '['
+	{0x00, 0x10, 0x08, 0x04, 0x02, 0x01, 0x00}, // '\\'
+	{0x0e, 0x02, 0x02, 0x02, 0x02, 0x02, 0x0e}, // ']'
+	{0x04, 0x0a, 0x11, 0x00, 0x00, 0x00, 0x00}, // '^'
+	{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x1f}, // '_'
+	{0x08, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00}, // '`'
+	{0x00, 0x00, 0x0e, 0x01, 0x0f, 0x11, 0x0f}, // 'a'
+	{0x10, 0x10, 0x16, 0x19, 0x11, 0x11, 0x1e}, // 'b'
+	{0x00, 0x00, 0x0e, 0x10, 0x10, 0x11, 0x0e}, // 'c'
+	{0x01, 0x01, 0x0d, 0x13, 0x11, 0x11, 0x0f}, // 'd'
+	{0x00, 0x00, 0x0e, 0x11, 0x1f, 0x10, 0x0e}, // 'e'
+	{0x06, 0x09, 0x08, 0x1c, 0x08, 0x08, 0x08}, // 'f'
+	{0x00, 0x0f, 0x11, 0x11, 0x0f, 0x01, 0x0e}, // 'g'
+	{0x10, 0x10, 0x16, 0x19, 0x11, 0x11, 0x11}, // 'h'
+	{0x04, 0x00, 0x0c, 0x04, 0x04, 0x04, 0x0e}, // 'i'
+	{0x02, 0x00, 0x06, 0x02, 0x02, 0x12, 0x0c}, // 'j'
+	{0x10, 0x10, 0x12, 0x14, 0x18, 0x14, 0x12}, // 'k'
+	{0x0c, 0x04, 0x04, 0x04, 0x04, 0x04, 0x0e}, // 'l'
+	{0x00, 0x00, 0x1a, 0x15, 0x15, 0x11, 0x11}, // 'm'
+	{0x00, 0x00, 0x16, 0x19, 0x11, 0x11, 0x11}, // 'n'
+	{0x00, 0x00, 0x0e, 0x11, 0x11, 0x11, 0x0e}, // 'o'
+	{0x00, 0x00, 0x1e, 0x11, 0x1e, 0x10, 0x10}, // 'p'
+	{0x00, 0x00, 0x0d, 0x13, 0x0f, 0x01, 0x01}, // 'q'
+	{0x00, 0x00, 0x16, 0x19, 0x10, 0x10, 0x10}, // 'r'
+	{0x00, 0x00, 0x0e, 0x10, 0x0e, 0x01, 0x1e}, // 's'
+	{0x08, 0x08, 0x1c, 0x08, 0x08, 0x09, 0x06}, // 't'
+	{0x00, 0x00, 0x11, 0x11, 0x11, 0x13, 0x0d}, // 'u'
+	{0x00, 0x00, 0x11, 0x11, 0x11, 0x0a, 0x04}, // 'v'
+	{0x00, 0x00, 0x11, 0x11, 0x15, 0x15, 0x0a}, // 'w'
+	{0x00, 0x00, 0x11, 0x0a, 0x04, 0x0a, 0x11}, // 'x'
+	{0x00, 0x00, 0x11, 0x11, 0x0f, 0x01, 0x0e}, // 'y'
+	{0x00, 0x00, 0x1f, 0x02, 0x04, 0x08, 0x1f}, // 'z'
+	{0x02, 0x04, 0x04, 0x08, 0x04, 0x04, 0x02}, // '{'
+	{0x04, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04}, // '|'
+	{0x08, 0x04, 0x04, 0x02, 0x04, 0x04, 0x08}, // '}'
+	{0x00, 0x00, 0x08, 0x15, 0x02, 0x00, 0x00}, // '~'
+}
@@ -0,0 +1,59 @@
+package nimsforestviewer
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Hand-rolled protobuf wire encoding for the few messages this package sends;
+// see the package doc for why there are no generated stubs.
+// The proto3 helpers skip zero values, matching what generated code emits.
+
+// appendProtoString writes a length-delimited string field, even when it is empty.
+func appendProtoString(b []byte, field int, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// appendProtoMessage writes an embedded message field.
+func appendProtoMessage(b []byte, field int, msg []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(msg)))
+	return append(b, msg...)
+}
+
+func appendProto3String(b []byte, field int, s string) []byte {
+	if s == "" {
+		return b
+	}
+	return appendProtoString(b, field, s)
+}
+
+func appendProto3Uint(b []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = binary.AppendUvarint(b, uint64(field)<<3)
+	return binary.AppendUvarint(b, v)
+}
+
+// appendProto3Int writes an int32 or int64 field; negative values are sign-extended.
+func appendProto3Int(b []byte, field int, v int) []byte {
+	return appendProto3Uint(b, field, uint64(int64(v)))
+}
+
+func appendProto3Bool(b []byte, field int, v bool) []byte {
+	if !v {
+		return b
+	}
+	return appendProto3Uint(b, field, 1)
+}
+
+func appendProto3Double(b []byte, field int, v float64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = binary.AppendUvarint(b, uint64(field)<<3|1)
+	return binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+}
@@ -0,0 +1,125 @@
+package nimsforestviewer
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"sync"
+
+	sprites "github.com/nimsforest/nimsforestsprites"
+)
+
+// SharedRenderer is a sprite renderer that several image targets can render
+// through, so a setup with two TVs and a video target holds one renderer instead
+// of three. Frames are rendered one at a time.
+//
+// Targets given a SharedRenderer use its options in place of their own sprite
+// options and don't close it; the caller closes it after closing the targets.
+type SharedRenderer struct {
+	mu       sync.Mutex
+	renderer *sprites.Renderer
+	opts     sprites.Options
+}
+
+// NewSharedRenderer creates a renderer that can be passed to several targets.
+func NewSharedRenderer(opts sprites.Options) (*SharedRenderer, error) {
+	renderer, err := sprites.New(opts)
+	if err != nil {
+		return nil, fmt.Errorf("create sprite renderer: %w", err)
+	}
+	return &SharedRenderer{renderer: renderer, opts: opts}, nil
+}
+
+// Options returns the options the renderer was created with.
+func (r *SharedRenderer) Options() sprites.Options {
+	return r.opts
+}
+
+// Render renders one frame. Each call returns a new image.
+func (r *SharedRenderer) Render(state sprites.State) image.Image {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.renderer.Render(state)
+}
+
+// encodedRenderer is implemented by sprite renderers that can encode frames
+// themselves. format is the requested "jpeg" or "png"; quality is 1-100, or 0 for
+// the encoder's default. The returned format is the one actually produced.
+type encodedRenderer interface {
+	RenderEncoded(state sprites.State, format string, quality int) ([]byte, string, error)
+}
+
+// canRenderEncoded reports whether the underlying renderer implements encodedRenderer.
+func (r *SharedRenderer) canRenderEncoded() bool {
+	_, ok := any(r.renderer).(encodedRenderer)
+	return ok
+}
+
+// RenderEncoded renders one frame encoded by the underlying renderer, which
+// should be asked for format. ok is false when the renderer can't encode or
+// produced a different format, and the caller should Render and encode the
+// frame itself.
+func (r *SharedRenderer) RenderEncoded(state sprites.State, format string, quality int) (data []byte, ok bool, err error) {
+	enc, ok := any(r.renderer).(encodedRenderer)
+	if !ok {
+		return nil, false, nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data, got, err := enc.RenderEncoded(state, format, quality)
+	if err != nil {
+		return nil, false, err
+	}
+	return data, got == format, nil
+}
+
+// Close releases the renderer.
+func (r *SharedRenderer) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.renderer.Close()
+}
+
+// spriteSource is the renderer an image target draws with, either its own or shared.
+type spriteSource struct {
+	sprites    *SharedRenderer
+	ownSprites bool // Created by the target, which closes it
+}
+
+// initSprites creates the target's own renderer from opts, or if a shared one was
+// given, returns the shared renderer's options for the target to use instead.
+func (s *spriteSource) initSprites(opts sprites.Options) (sprites.Options, error) {
+	if s.sprites != nil {
+		return s.sprites.Options(), nil
+	}
+	renderer, err := NewSharedRenderer(opts)
+	if err != nil {
+		return opts, err
+	}
+	s.sprites, s.ownSprites = renderer, true
+	return opts, nil
+}
+
+// placeholderFrame is a frame of opts' size showing msg, displayed in place of
+// rendered frames when no sprite renderer could be created.
+func placeholderFrame(opts sprites.Options, msg string) *image.RGBA {
+	width, height := opts.Width, opts.Height
+	if width <= 0 || height <= 0 {
+		width, height = 1920, 1080
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(DarkTheme.Background), image.Point{}, draw.Src)
+
+	scale := overlayScale(img.Bounds())
+	w, h := textSize(msg, scale)
+	drawText(img, (width-w)/2, (height-h)/2, msg, scale, color.RGBA{255, 255, 255, 255})
+	return img
+}
+
+// closeSprites closes the renderer if the target owns it.
+func (s *spriteSource) closeSprites() {
+	if s.sprites != nil && s.ownSprites {
+		s.sprites.Close()
+	}
+}
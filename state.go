@@ -1,24 +1,198 @@
 // Package nimsforestviewer provides a unified visualization viewer for Smart TVs and web browsers.
+//
+// GRPCTarget encodes the messages in proto/viewer.proto by hand (see protobuf.go)
+// and serves them over net/http's h2c support rather than using generated stubs,
+// so the package does not depend on google.golang.org/protobuf or grpc-go for a
+// single server-streaming method. TestWorldProtoMatchesSchema keeps the encoder,
+// the schema and the JSON model in step.
 package nimsforestviewer
 
+import (
+	"errors"
+	"fmt"
+)
+
 // ViewState represents the complete visualization state.
 type ViewState struct {
 	Lands   []LandView
 	Summary SummaryView
 }
 
+// RecomputeSummary derives Summary from Lands: land and manaland counts,
+// per-type process counts and RAM totals.
+func (s *ViewState) RecomputeSummary() {
+	s.Summary = summarize(s.Lands)
+}
+
+// Clone returns a deep copy of the state, including every land's process slices.
+func (s *ViewState) Clone() *ViewState {
+	if s == nil {
+		return nil
+	}
+	clone := &ViewState{Summary: s.Summary}
+	if s.Lands != nil {
+		clone.Lands = make([]LandView, len(s.Lands))
+		for i := range s.Lands {
+			clone.Lands[i] = s.Lands[i].Clone()
+		}
+	}
+	return clone
+}
+
 // LandView represents a single land/node in the visualization.
+// A land is placed at GridX/GridY when HasGridPosition is set or either
+// coordinate is non-zero; otherwise it is laid out automatically.
 type LandView struct {
-	ID           string
-	Hostname     string
-	GridX, GridY int
-	IsManaland   bool
-	Occupancy    float64
-	RAMTotal     uint64
-	RAMAllocated uint64
-	Trees        []ProcessView
-	Treehouses   []ProcessView
-	Nims         []ProcessView
+	ID              string
+	Hostname        string
+	GridX, GridY    int
+	HasGridPosition bool // Set to place a land at (0, 0) explicitly
+	IsManaland      bool
+	Occupancy       float64 // 0.0-1.0; the Viewer clamps values outside that range
+	Status          string  // LandStatusOK, LandStatusWarn or LandStatusCritical; set by the Viewer
+	RAMTotal        uint64
+	RAMAllocated    uint64
+	Trees           []ProcessView
+	Treehouses      []ProcessView
+	Nims            []ProcessView
+	HiddenProcesses int // Processes LimitProcesses folded into a MoreProcessesID process
+}
+
+// Land status values derived from occupancy.
+const (
+	LandStatusOK       = "ok"
+	LandStatusWarn     = "warn"
+	LandStatusCritical = "critical"
+)
+
+// Default occupancy thresholds for land status.
+const (
+	DefaultOccupancyWarn     = 0.6
+	DefaultOccupancyCritical = 0.85
+)
+
+// OccupancyStatus classifies an occupancy against warn and critical thresholds.
+func OccupancyStatus(occupancy, warn, critical float64) string {
+	switch {
+	case occupancy >= critical:
+		return LandStatusCritical
+	case occupancy >= warn:
+		return LandStatusWarn
+	default:
+		return LandStatusOK
+	}
+}
+
+// landStatus returns the land's Status, or the status under the default
+// thresholds when no Viewer has set one.
+func landStatus(land *LandView) string {
+	if land.Status != "" {
+		return land.Status
+	}
+	return OccupancyStatus(land.Occupancy, DefaultOccupancyWarn, DefaultOccupancyCritical)
+}
+
+// Positioned reports whether the land has an explicit grid position.
+func (l *LandView) Positioned() bool {
+	return l.HasGridPosition || l.GridX != 0 || l.GridY != 0
+}
+
+// Clone returns a copy of the land with its own process slices.
+func (l LandView) Clone() LandView {
+	l.Trees = cloneProcesses(l.Trees)
+	l.Treehouses = cloneProcesses(l.Treehouses)
+	l.Nims = cloneProcesses(l.Nims)
+	return l
+}
+
+func cloneProcesses(processes []ProcessView) []ProcessView {
+	if processes == nil {
+		return nil
+	}
+	clone := append([]ProcessView(nil), processes...)
+	for i := range clone {
+		if clone[i].Subjects != nil {
+			clone[i].Subjects = append([]string(nil), clone[i].Subjects...)
+		}
+	}
+	return clone
+}
+
+// Validate checks s for input a provider shouldn't produce: duplicate land IDs,
+// duplicate process IDs within a land, parent IDs naming no process on the
+// same land, and occupancy or progress outside 0-1.
+// The returned error lists every problem found.
+func (s *ViewState) Validate() error {
+	if s == nil {
+		return nil
+	}
+	var errs []error
+	lands := make(map[string]int, len(s.Lands))
+	for i, land := range s.Lands {
+		if first, ok := lands[land.ID]; ok {
+			errs = append(errs, fmt.Errorf("land %d: duplicate ID %q, also used by land %d", i, land.ID, first))
+		} else {
+			lands[land.ID] = i
+		}
+		if land.Occupancy < 0 || land.Occupancy > 1 {
+			errs = append(errs, fmt.Errorf("land %q: occupancy %v outside 0-1", land.ID, land.Occupancy))
+		}
+
+		procs := make(map[string]bool)
+		for _, proc := range land.AllProcesses() {
+			if procs[proc.ID] {
+				errs = append(errs, fmt.Errorf("land %q: duplicate process ID %q", land.ID, proc.ID))
+			}
+			procs[proc.ID] = true
+			if proc.Progress < 0 || proc.Progress > 1 {
+				errs = append(errs, fmt.Errorf("land %q: process %q progress %v outside 0-1", land.ID, proc.ID, proc.Progress))
+			}
+		}
+		for _, proc := range land.AllProcesses() {
+			if proc.ParentID != "" && !procs[proc.ParentID] {
+				errs = append(errs, fmt.Errorf("land %q: process %q has unknown parent %q", land.ID, proc.ID, proc.ParentID))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Sanitize clamps land occupancy and process progress into 0-1, treating NaN
+// as 0, and returns how many values it changed. The Viewer runs it on every
+// state so the renderers never see out-of-range values.
+func (s *ViewState) Sanitize() int {
+	if s == nil {
+		return 0
+	}
+	clamped := 0
+	fix := func(f *float64) {
+		if v := clamp01(*f); v != *f {
+			*f = v
+			clamped++
+		}
+	}
+	for i := range s.Lands {
+		land := &s.Lands[i]
+		fix(&land.Occupancy)
+		for _, procs := range [][]ProcessView{land.Trees, land.Treehouses, land.Nims} {
+			for j := range procs {
+				fix(&procs[j].Progress)
+			}
+		}
+	}
+	return clamped
+}
+
+// clamp01 limits f to 0-1, mapping NaN to 0.
+func clamp01(f float64) float64 {
+	switch {
+	case f > 1:
+		return 1
+	case f >= 0:
+		return f
+	default: // Negative or NaN
+		return 0
+	}
 }
 
 // AllProcesses returns all processes on this land.
@@ -36,9 +210,20 @@ type ProcessView struct {
 	Name         string
 	Type         string // "tree", "treehouse", "nim"
 	RAMAllocated uint64
-	Progress     float64
+	Progress     float64  // 0.0-1.0; the Viewer clamps values outside that range
+	State        string   // ProcessStateRunning, ProcessStateFailed, etc.; empty means running
+	Subjects     []string // Message subjects the process publishes or subscribes to
+	ParentID     string   // ID of the process this one runs inside, e.g. a treehouse's tree
 }
 
+// Process lifecycle states.
+const (
+	ProcessStatePending   = "pending"
+	ProcessStateRunning   = "running"
+	ProcessStateCompleted = "completed"
+	ProcessStateFailed    = "failed"
+)
+
 // SummaryView contains aggregate statistics.
 type SummaryView struct {
 	TotalLands      int
@@ -49,3 +234,20 @@ type SummaryView struct {
 	TotalRAM        uint64
 	AllocatedRAM    uint64
 }
+
+// summarize derives aggregate statistics from a set of lands.
+func summarize(lands []LandView) SummaryView {
+	var s SummaryView
+	for _, land := range lands {
+		s.TotalLands++
+		if land.IsManaland {
+			s.TotalManalands++
+		}
+		s.TotalTrees += len(land.Trees)
+		s.TotalTreehouses += len(land.Treehouses)
+		s.TotalNims += len(land.Nims)
+		s.TotalRAM += land.RAMTotal
+		s.AllocatedRAM += land.RAMAllocated
+	}
+	return s
+}
@@ -1,11 +1,35 @@
 package nimsforestviewer
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
 // StateProvider provides the current ViewState for visualization.
 type StateProvider interface {
 	// GetViewState returns the current visualization state.
 	GetViewState() (*ViewState, error)
 }
 
+// StateProviderContext is an optional interface for providers whose fetch can be
+// cancelled, e.g. ones backed by HTTP. The Viewer prefers it over GetViewState.
+type StateProviderContext interface {
+	StateProvider
+
+	// GetViewStateContext returns the current visualization state, giving up when ctx is done.
+	GetViewStateContext(ctx context.Context) (*ViewState, error)
+}
+
+// getViewState fetches state from p, passing ctx along when p supports it.
+func getViewState(ctx context.Context, p StateProvider) (*ViewState, error) {
+	if pc, ok := p.(StateProviderContext); ok {
+		return pc.GetViewStateContext(ctx)
+	}
+	return p.GetViewState()
+}
+
 // StaticStateProvider wraps a fixed ViewState.
 type StaticStateProvider struct {
 	state *ViewState
@@ -35,3 +59,80 @@ func NewCallbackStateProvider(fn func() (*ViewState, error)) *CallbackStateProvi
 func (p *CallbackStateProvider) GetViewState() (*ViewState, error) {
 	return p.fn()
 }
+
+// MergingStateProvider combines the lands of several providers into one world,
+// e.g. when each regional controller reports its own subset of lands.
+type MergingStateProvider struct {
+	providers       []StateProvider
+	continueOnError bool
+
+	mu       sync.Mutex
+	failures []error
+}
+
+// NewMergingStateProvider creates a StateProvider that concatenates the lands of
+// all given providers and recomputes the summary across them.
+func NewMergingStateProvider(providers ...StateProvider) *MergingStateProvider {
+	return &MergingStateProvider{providers: providers}
+}
+
+// SetContinueOnError controls whether a failing provider is skipped (true) or
+// fails the whole merge (false, the default). Skipped failures are available
+// from Failures.
+func (p *MergingStateProvider) SetContinueOnError(enable bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.continueOnError = enable
+}
+
+// Failures returns the errors of providers skipped during the last merge.
+// Each error names the index of the provider that failed.
+func (p *MergingStateProvider) Failures() []error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]error(nil), p.failures...)
+}
+
+// GetViewState implements StateProvider.
+func (p *MergingStateProvider) GetViewState() (*ViewState, error) {
+	return p.GetViewStateContext(context.Background())
+}
+
+// GetViewStateContext implements StateProviderContext.
+// ctx is passed on to providers that support it.
+func (p *MergingStateProvider) GetViewStateContext(ctx context.Context) (*ViewState, error) {
+	p.mu.Lock()
+	continueOnError := p.continueOnError
+	p.mu.Unlock()
+
+	merged := &ViewState{}
+	var failures []error
+	for i, provider := range p.providers {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		state, err := getViewState(ctx, provider)
+		if err != nil {
+			err = fmt.Errorf("provider %d: %w", i, err)
+			if !continueOnError {
+				return nil, err
+			}
+			failures = append(failures, err)
+			continue
+		}
+		if state != nil {
+			merged.Lands = append(merged.Lands, state.Lands...)
+		}
+	}
+
+	p.mu.Lock()
+	p.failures = failures
+	p.mu.Unlock()
+
+	if len(p.providers) > 0 && len(failures) == len(p.providers) {
+		return nil, fmt.Errorf("all providers failed: %w", errors.Join(failures...))
+	}
+
+	merged.RecomputeSummary()
+	return merged, nil
+}
@@ -0,0 +1,274 @@
+package nimsforestviewer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MappingConfig tells a MapStateProvider where to find ViewState fields in a
+// decoded JSON document. Paths are dot-separated object keys, with numeric
+// segments indexing arrays, e.g. "data.nodes" or "meta.hosts.0.name".
+// Empty paths leave the field unset.
+type MappingConfig struct {
+	// Lands is the path of the array of lands, from the document root. Required.
+	Lands string
+
+	// Land field paths, relative to each element of Lands.
+	ID           string // Defaults to the hostname when empty or missing
+	Hostname     string
+	Occupancy    string // 0.0-1.0; derived from RAM when unset
+	RAMTotal     string
+	RAMAllocated string
+	IsManaland   string
+	GridX        string
+	GridY        string
+
+	// Processes is the path of each land's process array, relative to the land.
+	Processes string
+
+	// Process field paths, relative to each element of Processes.
+	ProcessID       string
+	ProcessName     string
+	ProcessType     string // "tree", "treehouse" or "nim"; other processes are skipped
+	ProcessRAM      string
+	ProcessProgress string
+	ProcessState    string // See ProcessStateRunning and friends
+}
+
+// MapStateProvider builds ViewState from loosely structured JSON, such as the
+// output of a monitoring API, using the paths in a MappingConfig.
+type MapStateProvider struct {
+	fetch   func() (map[string]any, error)
+	mapping MappingConfig
+}
+
+// NewMapStateProvider creates a StateProvider that maps the documents returned by
+// fetch into ViewState. fetch typically decodes an HTTP response with encoding/json.
+func NewMapStateProvider(fetch func() (map[string]any, error), mapping MappingConfig) *MapStateProvider {
+	return &MapStateProvider{fetch: fetch, mapping: mapping}
+}
+
+// GetViewState implements StateProvider.
+func (p *MapStateProvider) GetViewState() (*ViewState, error) {
+	doc, err := p.fetch()
+	if err != nil {
+		return nil, err
+	}
+	if p.mapping.Lands == "" {
+		return nil, fmt.Errorf("mapping has no lands path")
+	}
+
+	raw, ok := lookupPath(doc, p.mapping.Lands)
+	if !ok {
+		return nil, fmt.Errorf("lands path %q not found", p.mapping.Lands)
+	}
+	items, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("lands path %q is %T, not an array", p.mapping.Lands, raw)
+	}
+
+	state := &ViewState{Lands: make([]LandView, 0, len(items))}
+	for i, item := range items {
+		land, err := p.mapLand(item)
+		if err != nil {
+			return nil, fmt.Errorf("land %d: %w", i, err)
+		}
+		state.Lands = append(state.Lands, land)
+	}
+	state.RecomputeSummary()
+	return state, nil
+}
+
+// mapLand maps one element of the lands array.
+func (p *MapStateProvider) mapLand(item any) (LandView, error) {
+	m := p.mapping
+	var land LandView
+	var gridX, gridY float64
+	var hasOccupancy, hasX, hasY bool
+
+	fields := []error{
+		mapString(item, m.Hostname, &land.Hostname),
+		mapString(item, m.ID, &land.ID),
+		mapFloat(item, m.Occupancy, &land.Occupancy, &hasOccupancy),
+		mapUint(item, m.RAMTotal, &land.RAMTotal),
+		mapUint(item, m.RAMAllocated, &land.RAMAllocated),
+		mapBool(item, m.IsManaland, &land.IsManaland),
+		mapFloat(item, m.GridX, &gridX, &hasX),
+		mapFloat(item, m.GridY, &gridY, &hasY),
+	}
+	for _, err := range fields {
+		if err != nil {
+			return land, err
+		}
+	}
+
+	if land.ID == "" {
+		land.ID = land.Hostname
+	}
+	if !hasOccupancy && land.RAMTotal > 0 {
+		land.Occupancy = float64(land.RAMAllocated) / float64(land.RAMTotal)
+	}
+	if hasX || hasY {
+		land.GridX, land.GridY = int(gridX), int(gridY)
+		land.HasGridPosition = true
+	}
+
+	if m.Processes == "" {
+		return land, nil
+	}
+	raw, ok := lookupPath(item, m.Processes)
+	if !ok || raw == nil {
+		return land, nil // A land without processes
+	}
+	procs, ok := raw.([]any)
+	if !ok {
+		return land, fmt.Errorf("processes path %q is %T, not an array", m.Processes, raw)
+	}
+	for i, procItem := range procs {
+		var proc ProcessView
+		fields := []error{
+			mapString(procItem, m.ProcessID, &proc.ID),
+			mapString(procItem, m.ProcessName, &proc.Name),
+			mapString(procItem, m.ProcessType, &proc.Type),
+			mapUint(procItem, m.ProcessRAM, &proc.RAMAllocated),
+			mapFloat(procItem, m.ProcessProgress, &proc.Progress, nil),
+			mapString(procItem, m.ProcessState, &proc.State),
+		}
+		for _, err := range fields {
+			if err != nil {
+				return land, fmt.Errorf("process %d: %w", i, err)
+			}
+		}
+		switch proc.Type {
+		case "tree":
+			land.Trees = append(land.Trees, proc)
+		case "treehouse":
+			land.Treehouses = append(land.Treehouses, proc)
+		case "nim":
+			land.Nims = append(land.Nims, proc)
+		}
+	}
+	return land, nil
+}
+
+// lookupPath walks a dot-separated path through decoded JSON.
+func lookupPath(v any, path string) (any, bool) {
+	if path == "" {
+		return v, true
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			next, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			v = next
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// mapString sets *dst from path, accepting strings and numbers.
+// Missing values and empty paths leave *dst unchanged.
+func mapString(item any, path string, dst *string) error {
+	if path == "" {
+		return nil
+	}
+	v, ok := lookupPath(item, path)
+	if !ok || v == nil {
+		return nil
+	}
+	switch s := v.(type) {
+	case string:
+		*dst = s
+	case float64:
+		*dst = strconv.FormatFloat(s, 'f', -1, 64)
+	case json.Number:
+		*dst = s.String()
+	default:
+		return fmt.Errorf("%s: expected a string, got %T", path, v)
+	}
+	return nil
+}
+
+// mapFloat sets *dst from path, accepting numbers and numeric strings.
+func mapFloat(item any, path string, dst *float64, found *bool) error {
+	if path == "" {
+		return nil
+	}
+	v, ok := lookupPath(item, path)
+	if !ok || v == nil {
+		return nil
+	}
+	var f float64
+	var err error
+	switch n := v.(type) {
+	case float64:
+		f = n
+	case int:
+		f = float64(n)
+	case json.Number:
+		f, err = n.Float64()
+	case string:
+		f, err = strconv.ParseFloat(n, 64)
+	default:
+		err = fmt.Errorf("expected a number, got %T", v)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	*dst = f
+	if found != nil {
+		*found = true
+	}
+	return nil
+}
+
+// mapUint sets *dst from path, accepting non-negative numbers and numeric strings.
+func mapUint(item any, path string, dst *uint64) error {
+	var f float64
+	var found bool
+	if err := mapFloat(item, path, &f, &found); err != nil || !found {
+		return err
+	}
+	if f < 0 {
+		return fmt.Errorf("%s: negative value %v", path, f)
+	}
+	*dst = uint64(f)
+	return nil
+}
+
+// mapBool sets *dst from path, accepting booleans and "true"/"false" strings.
+func mapBool(item any, path string, dst *bool) error {
+	if path == "" {
+		return nil
+	}
+	v, ok := lookupPath(item, path)
+	if !ok || v == nil {
+		return nil
+	}
+	switch b := v.(type) {
+	case bool:
+		*dst = b
+	case string:
+		parsed, err := strconv.ParseBool(b)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		*dst = parsed
+	default:
+		return fmt.Errorf("%s: expected a boolean, got %T", path, v)
+	}
+	return nil
+}
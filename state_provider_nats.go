@@ -0,0 +1,185 @@
+package nimsforestviewer
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrNoSnapshot is returned by NATSStateProvider.GetViewState until the first
+// snapshot has been received.
+var ErrNoSnapshot = errors.New("no state snapshot received yet")
+
+// NATSStateProvider serves the latest WorldJSON snapshot published on a NATS subject.
+type NATSStateProvider struct {
+	serverURL   string
+	subject     string
+	cfg         natsConfig
+	dialTimeout time.Duration
+	maxBackoff  time.Duration
+
+	mu       sync.Mutex
+	latest   *ViewState
+	received time.Time
+	connErr  error // Why the provider is currently disconnected, if it is
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NATSOption configures a NATSStateProvider.
+type NATSOption func(*NATSStateProvider)
+
+// WithNATSCredentials authenticates with a user name and password.
+// Credentials in the server URL are used when this isn't given.
+func WithNATSCredentials(username, password string) NATSOption {
+	return func(p *NATSStateProvider) {
+		p.cfg.username = username
+		p.cfg.password = password
+	}
+}
+
+// WithNATSToken authenticates with a token.
+func WithNATSToken(token string) NATSOption {
+	return func(p *NATSStateProvider) {
+		p.cfg.token = token
+	}
+}
+
+// WithNATSTLSConfig sets the TLS configuration used for tls:// servers and
+// servers that require TLS.
+func WithNATSTLSConfig(cfg *tls.Config) NATSOption {
+	return func(p *NATSStateProvider) {
+		p.cfg.tlsConfig = cfg
+	}
+}
+
+// WithNATSReconnectBackoff sets the longest wait between reconnect attempts.
+// Waits start at one second and double up to this. Defaults to 30s.
+func WithNATSReconnectBackoff(max time.Duration) NATSOption {
+	return func(p *NATSStateProvider) {
+		p.maxBackoff = max
+	}
+}
+
+// NewNATSStateProvider subscribes to subject on the NATS server at serverURL,
+// e.g. "nats://controller:4222", and keeps the latest WorldJSON snapshot
+// published there. It connects in the background and reconnects after failures,
+// so the server doesn't need to be reachable yet. Call Close to unsubscribe.
+func NewNATSStateProvider(serverURL, subject string, opts ...NATSOption) (*NATSStateProvider, error) {
+	if subject == "" {
+		return nil, fmt.Errorf("subject is required")
+	}
+
+	p := &NATSStateProvider{
+		serverURL:   serverURL,
+		subject:     subject,
+		dialTimeout: 10 * time.Second,
+		maxBackoff:  30 * time.Second,
+		cfg: natsConfig{
+			pingInterval: 30 * time.Second,
+		},
+		done: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	// Reject malformed URLs up front rather than retrying them forever
+	if _, _, err := parseNATSURL(serverURL); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	go p.run(ctx)
+	return p, nil
+}
+
+// GetViewState implements StateProvider by returning the latest snapshot.
+// Before the first one arrives it returns an error wrapping ErrNoSnapshot.
+func (p *NATSStateProvider) GetViewState() (*ViewState, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.latest == nil {
+		if p.connErr != nil {
+			return nil, fmt.Errorf("%w on %q: %w", ErrNoSnapshot, p.subject, p.connErr)
+		}
+		return nil, fmt.Errorf("%w on %q", ErrNoSnapshot, p.subject)
+	}
+	return p.latest.Clone(), nil
+}
+
+// LastReceived returns when the latest snapshot arrived, or the zero time.
+func (p *NATSStateProvider) LastReceived() time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.received
+}
+
+// Close stops the subscription and waits for the connection to close.
+func (p *NATSStateProvider) Close() error {
+	p.cancel()
+	<-p.done
+	return nil
+}
+
+// run keeps a subscription open until ctx is cancelled.
+func (p *NATSStateProvider) run(ctx context.Context) {
+	defer close(p.done)
+	backoff := time.Second
+	for {
+		dialCtx, cancel := context.WithTimeout(ctx, p.dialTimeout)
+		client, err := dialNATS(dialCtx, p.serverURL, p.subject, p.cfg, p.receive)
+		cancel()
+
+		if err == nil {
+			p.setConnErr(nil)
+			backoff = time.Second
+			select {
+			case <-client.done:
+				err = client.err
+				if err == nil {
+					err = errors.New("connection closed")
+				}
+			case <-ctx.Done():
+				client.close()
+				<-client.done
+				return
+			}
+		}
+		p.setConnErr(fmt.Errorf("connect to %s: %w", p.serverURL, err))
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		backoff = min(2*backoff, p.maxBackoff)
+	}
+}
+
+// receive decodes a snapshot. Messages that aren't WorldJSON are ignored, so a
+// stray publish can't wipe out the last good state.
+func (p *NATSStateProvider) receive(payload []byte) {
+	var world WorldJSON
+	if err := json.Unmarshal(payload, &world); err != nil {
+		return
+	}
+	state := JSONToViewState(world)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.latest = state
+	p.received = time.Now()
+}
+
+func (p *NATSStateProvider) setConnErr(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.connErr = err
+}
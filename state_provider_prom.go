@@ -0,0 +1,211 @@
+package nimsforestviewer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// promMaxProcesses bounds the processes synthesized per host and type, so a
+// query returning a huge value can't exhaust memory.
+const promMaxProcesses = 1000
+
+// PromQueries configures the PromQL instant queries a PromStateProvider runs.
+// Each query should return one series per host, e.g.
+// "node_memory_MemTotal_bytes" or `count by (instance) (nim_up)`.
+// Empty queries are skipped.
+type PromQueries struct {
+	// HostLabel is the label that identifies a host. Defaults to "instance".
+	HostLabel string
+
+	RAMTotal     string // Bytes
+	RAMAllocated string // Bytes
+
+	// Process counts per host. Prometheus has no per-process identity, so each
+	// count becomes that many processes named "tree 1", "tree 2" and so on.
+	Trees      string
+	Treehouses string
+	Nims       string
+}
+
+// PromStateProvider builds ViewState from Prometheus instant queries.
+type PromStateProvider struct {
+	queryURL string
+	queries  PromQueries
+	client   *http.Client
+}
+
+// PromOption configures a PromStateProvider.
+type PromOption func(*PromStateProvider)
+
+// WithPromHTTPClient sets the HTTP client used for queries, e.g. to add
+// authentication. Defaults to a client with a 10s timeout.
+func WithPromHTTPClient(client *http.Client) PromOption {
+	return func(p *PromStateProvider) {
+		p.client = client
+	}
+}
+
+// NewPromStateProvider creates a provider that queries the Prometheus server at
+// queryURL, e.g. "http://prometheus:9090".
+func NewPromStateProvider(queryURL string, queries PromQueries, opts ...PromOption) (*PromStateProvider, error) {
+	if _, err := url.Parse(queryURL); err != nil {
+		return nil, fmt.Errorf("parse query URL: %w", err)
+	}
+	if queries.HostLabel == "" {
+		queries.HostLabel = "instance"
+	}
+
+	p := &PromStateProvider{
+		queryURL: strings.TrimSuffix(queryURL, "/"),
+		queries:  queries,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p, nil
+}
+
+// GetViewState implements StateProvider.
+func (p *PromStateProvider) GetViewState() (*ViewState, error) {
+	return p.GetViewStateContext(context.Background())
+}
+
+// GetViewStateContext implements StateProviderContext.
+// Hosts that appear in any query's results become lands, ordered by name.
+func (p *PromStateProvider) GetViewStateContext(ctx context.Context) (*ViewState, error) {
+	lands := make(map[string]*LandView)
+	land := func(host string) *LandView {
+		if l, ok := lands[host]; ok {
+			return l
+		}
+		l := &LandView{ID: host, Hostname: host}
+		lands[host] = l
+		return l
+	}
+
+	apply := []struct {
+		query string
+		set   func(l *LandView, v float64)
+	}{
+		{p.queries.RAMTotal, func(l *LandView, v float64) { l.RAMTotal = uint64(max(v, 0)) }},
+		{p.queries.RAMAllocated, func(l *LandView, v float64) { l.RAMAllocated = uint64(max(v, 0)) }},
+		{p.queries.Trees, func(l *LandView, v float64) { l.Trees = promProcesses(l.ID, "tree", v) }},
+		{p.queries.Treehouses, func(l *LandView, v float64) { l.Treehouses = promProcesses(l.ID, "treehouse", v) }},
+		{p.queries.Nims, func(l *LandView, v float64) { l.Nims = promProcesses(l.ID, "nim", v) }},
+	}
+	for _, a := range apply {
+		if a.query == "" {
+			continue
+		}
+		values, err := p.query(ctx, a.query)
+		if err != nil {
+			return nil, fmt.Errorf("query %q: %w", a.query, err)
+		}
+		for host, v := range values {
+			a.set(land(host), v)
+		}
+	}
+
+	hosts := make([]string, 0, len(lands))
+	for host := range lands {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	state := &ViewState{Lands: make([]LandView, 0, len(hosts))}
+	for _, host := range hosts {
+		l := lands[host]
+		if l.RAMTotal > 0 {
+			l.Occupancy = float64(l.RAMAllocated) / float64(l.RAMTotal)
+		}
+		state.Lands = append(state.Lands, *l)
+	}
+	state.RecomputeSummary()
+	return state, nil
+}
+
+// promResponse is the part of a Prometheus /api/v1/query response the provider reads.
+type promResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Metric map[string]string `json:"metric"`
+			Value  [2]any            `json:"value"` // [unix time, "value"]
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// query runs an instant query and returns its values keyed by host label.
+// Series without the host label are ignored; if several series share a host,
+// their values are summed.
+func (p *PromStateProvider) query(ctx context.Context, query string) (map[string]float64, error) {
+	u := p.queryURL + "/api/v1/query?" + url.Values{"query": {query}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	var result promResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("HTTP %d: decode response: %w", resp.StatusCode, err)
+	}
+	if result.Status != "success" {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, result.Error)
+	}
+	if result.Data.ResultType != "vector" {
+		return nil, fmt.Errorf("expected a vector result, got %s", result.Data.ResultType)
+	}
+
+	values := make(map[string]float64)
+	for _, series := range result.Data.Result {
+		host, ok := series.Metric[p.queries.HostLabel]
+		if !ok {
+			continue
+		}
+		s, _ := series.Value[1].(string)
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("value %q for %s: %w", s, host, err)
+		}
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			continue // No usable value, e.g. a ratio with a zero denominator
+		}
+		values[host] += v
+	}
+	return values, nil
+}
+
+// promProcesses creates n placeholder processes of procType on host.
+func promProcesses(host, procType string, n float64) []ProcessView {
+	count := int(min(max(n, 0), promMaxProcesses))
+	procs := make([]ProcessView, count)
+	for i := range procs {
+		procs[i] = ProcessView{
+			ID:   fmt.Sprintf("%s/%s-%d", host, procType, i+1),
+			Name: fmt.Sprintf("%s %d", procType, i+1),
+			Type: procType,
+		}
+	}
+	return procs
+}
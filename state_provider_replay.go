@@ -0,0 +1,146 @@
+package nimsforestviewer
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ReplayStateProvider plays back a recorded sequence of states, advancing
+// through them by wall-clock time, for demos and regression tests.
+type ReplayStateProvider struct {
+	frames   []*ViewState
+	interval time.Duration // Time each frame is shown at rate 1
+	rate     float64
+	loop     bool
+	offsets  []time.Duration // Recorded time of each frame since the first; nil uses interval
+
+	mu    sync.Mutex
+	start time.Time // Set by the first GetViewState
+}
+
+// ReplayOption configures a ReplayStateProvider.
+type ReplayOption func(*ReplayStateProvider)
+
+// WithReplayLoop starts over from the first frame after the last one.
+// Without it playback stops on the last frame.
+func WithReplayLoop(loop bool) ReplayOption {
+	return func(p *ReplayStateProvider) {
+		p.loop = loop
+	}
+}
+
+// WithReplayRate sets the playback speed: 2 plays twice as fast, 0.5 half as fast.
+// Defaults to 1.
+func WithReplayRate(rate float64) ReplayOption {
+	return func(p *ReplayStateProvider) {
+		p.rate = rate
+	}
+}
+
+// WithReplayInterval sets how long each frame is shown at rate 1. Defaults to
+// one second; match it to the update interval the frames were recorded at.
+func WithReplayInterval(d time.Duration) ReplayOption {
+	return func(p *ReplayStateProvider) {
+		p.interval = d
+	}
+}
+
+// WithReplayTimestamps replays frames with the spacing they were recorded at,
+// such as the times returned by ReadRecording, instead of a fixed interval.
+// times must hold one entry per frame, in order. When looping, the last frame
+// is shown for the replay interval before starting over.
+func WithReplayTimestamps(times []time.Time) ReplayOption {
+	return func(p *ReplayStateProvider) {
+		p.offsets = make([]time.Duration, len(times))
+		for i, t := range times {
+			p.offsets[i] = t.Sub(times[0])
+		}
+	}
+}
+
+// NewReplayStateProvider creates a provider that replays frames in order.
+// Playback starts with the first call to GetViewState.
+func NewReplayStateProvider(frames []*ViewState, opts ...ReplayOption) (*ReplayStateProvider, error) {
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("no frames to replay")
+	}
+
+	p := &ReplayStateProvider{
+		frames:   frames,
+		interval: time.Second,
+		rate:     1,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if p.rate <= 0 {
+		return nil, fmt.Errorf("replay rate must be positive, got %v", p.rate)
+	}
+	if p.interval <= 0 {
+		return nil, fmt.Errorf("replay interval must be positive, got %v", p.interval)
+	}
+	if p.offsets != nil {
+		if len(p.offsets) != len(frames) {
+			return nil, fmt.Errorf("got %d timestamps for %d frames", len(p.offsets), len(frames))
+		}
+		if !slices.IsSorted(p.offsets) {
+			return nil, fmt.Errorf("timestamps are not in order")
+		}
+	}
+	return p, nil
+}
+
+// GetViewState implements StateProvider by returning the frame due at the
+// current playback position.
+func (p *ReplayStateProvider) GetViewState() (*ViewState, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if p.start.IsZero() {
+		p.start = now
+	}
+	return p.frames[p.frameAt(now.Sub(p.start))].Clone(), nil
+}
+
+// Reset restarts playback from the first frame on the next GetViewState.
+func (p *ReplayStateProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.start = time.Time{}
+}
+
+// Done reports whether playback has reached the last frame. It never does when looping.
+func (p *ReplayStateProvider) Done() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.loop || p.start.IsZero() {
+		return false
+	}
+	return p.frameAt(time.Since(p.start)) == len(p.frames)-1
+}
+
+// frameAt returns the index of the frame shown after elapsed wall-clock time.
+func (p *ReplayStateProvider) frameAt(elapsed time.Duration) int {
+	if p.offsets != nil {
+		pos := time.Duration(float64(elapsed) * p.rate)
+		if p.loop {
+			pos %= p.offsets[len(p.offsets)-1] + p.interval
+		}
+		// The last frame recorded at or before pos
+		i, found := slices.BinarySearch(p.offsets, pos)
+		if !found {
+			i--
+		}
+		return max(i, 0)
+	}
+
+	i := int(float64(elapsed) * p.rate / float64(p.interval))
+	if p.loop {
+		return i % len(p.frames)
+	}
+	return min(i, len(p.frames)-1)
+}
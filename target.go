@@ -1,10 +1,15 @@
 package nimsforestviewer
 
-import "context"
+import (
+	"context"
+	"image"
+	"sync/atomic"
+)
 
 // Target represents a visualization output destination.
 type Target interface {
 	// Update sends new state to the target.
+	// The Viewer passes each target its own copy of the state.
 	Update(ctx context.Context, state *ViewState) error
 
 	// Close cleans up the target.
@@ -13,3 +18,66 @@ type Target interface {
 	// Name returns a descriptive name for logging.
 	Name() string
 }
+
+// HealthChecker is implemented by targets that can tell whether they are reaching
+// their output, such as a TV. Targets that don't implement it are assumed healthy.
+type HealthChecker interface {
+	// Health returns nil if the target is healthy, or the reason it is not.
+	Health() error
+}
+
+// Validator is implemented by targets that can check their configuration without
+// displaying anything, such as whether a TV is reachable. Viewer.ValidateTargets
+// runs it to pre-flight a setup. Targets that don't implement it are assumed valid.
+type Validator interface {
+	// Validate returns nil if the target looks able to display, or the reason it is not.
+	Validate(ctx context.Context) error
+}
+
+// RefreshingTarget is implemented by targets that can ask for an update outside
+// the regular interval, e.g. when a user asks for fresh data. Viewer.AddTarget
+// passes it Viewer.Refresh.
+type RefreshingTarget interface {
+	SetRefresh(refresh func())
+}
+
+// ImageTarget is implemented by targets that display sprite-rendered frames.
+// A Viewer created with WithFrameRenderer renders each update once and passes
+// every ImageTarget its own copy of the frame instead of calling Update.
+type ImageTarget interface {
+	Target
+	// UpdateImage displays a frame rendered from the current state.
+	UpdateImage(ctx context.Context, img image.Image) error
+}
+
+// stateImageTarget is implemented by this package's ImageTargets. Unlike
+// UpdateImage, updateImage is given the pixel rects of the frame's grid cells
+// with failed processes, as from failedCells, so it can mark them.
+type stateImageTarget interface {
+	updateImage(ctx context.Context, img image.Image, failed []image.Rectangle) error
+}
+
+// FrameStatsReporter is implemented by image targets that can tell what their
+// most recent update produced, for UpdateStats.
+type FrameStatsReporter interface {
+	// LastFrameStats returns the size in bytes of the last encoded frame and
+	// whether it was not sent because it matched the previous one.
+	LastFrameStats() (size int, unchanged bool)
+}
+
+// frameStats implements FrameStatsReporter for the image targets that embed it.
+type frameStats struct {
+	size      atomic.Int64
+	unchanged atomic.Bool
+}
+
+// recordFrame records the outcome of encoding a frame of size bytes.
+func (s *frameStats) recordFrame(size int, unchanged bool) {
+	s.size.Store(int64(size))
+	s.unchanged.Store(unchanged)
+}
+
+// LastFrameStats implements FrameStatsReporter.
+func (s *frameStats) LastFrameStats() (size int, unchanged bool) {
+	return int(s.size.Load()), s.unchanged.Load()
+}
@@ -0,0 +1,332 @@
+package nimsforestviewer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	sprites "github.com/nimsforest/nimsforestsprites"
+)
+
+// defaultCastPort is the port Cast devices listen on.
+const defaultCastPort = 8009
+
+// ChromecastDevice identifies a Chromecast or other Cast-enabled display,
+// typically found via mDNS discovery of _googlecast._tcp.
+type ChromecastDevice struct {
+	Name string
+	Host string // IP address or hostname
+	Port int    // Defaults to 8009
+}
+
+// ChromecastTarget displays static images on a Chromecast via the Cast media protocol.
+// Frames are rendered and encoded exactly like SmartTVTarget; only the transport differs.
+// The Chromecast fetches each frame from a small HTTP server run by the target.
+type ChromecastTarget struct {
+	device      ChromecastDevice
+	spriteOpts  sprites.Options
+	viewport    *Viewport
+	listener    net.Listener
+	httpServer  *http.Server
+	localIP     string
+	dialTimeout time.Duration
+
+	mu             sync.Mutex
+	conn           *castConn
+	transportID    string
+	seq            int
+	lastImageBytes []byte // Last frame the device loaded, to skip redundant updates
+	frame          []byte // Frame served to the device
+	sendErr        error  // Result of the most recent send, reported by Health
+	closeOnce      sync.Once
+	spriteSource
+	themer
+	overlays
+	frameStats
+}
+
+// CastOption configures a ChromecastTarget.
+type CastOption func(*ChromecastTarget)
+
+// WithCastSpriteOptions sets the sprite renderer options.
+func WithCastSpriteOptions(opts sprites.Options) CastOption {
+	return func(t *ChromecastTarget) {
+		t.spriteOpts = opts
+	}
+}
+
+// WithCastSharedRenderer renders frames through r instead of a renderer of the
+// target's own. The renderer's options replace WithCastSpriteOptions.
+func WithCastSharedRenderer(r *SharedRenderer) CastOption {
+	return func(t *ChromecastTarget) {
+		t.sprites = r
+	}
+}
+
+// WithCastViewport crops the rendered frame to a region of the land grid, like WithViewport.
+func WithCastViewport(x, y, w, h int) CastOption {
+	return func(t *ChromecastTarget) {
+		t.viewport = &Viewport{X: x, Y: y, Width: w, Height: h}
+	}
+}
+
+// WithCastLocalIP sets the address the Chromecast uses to fetch frames.
+// Defaults to the IP of the interface used for outbound traffic.
+func WithCastLocalIP(ip string) CastOption {
+	return func(t *ChromecastTarget) {
+		t.localIP = ip
+	}
+}
+
+// WithCastDialTimeout sets how long to wait when connecting to the device. Defaults to 10s.
+func WithCastDialTimeout(d time.Duration) CastOption {
+	return func(t *ChromecastTarget) {
+		t.dialTimeout = d
+	}
+}
+
+// WithCastTimestampOverlay draws the current time on each frame, like WithTimestampOverlay.
+func WithCastTimestampOverlay(enable bool) CastOption {
+	return func(t *ChromecastTarget) {
+		t.timestamp = enable
+	}
+}
+
+// WithCastOverlayText draws a text banner in a corner of each frame, like WithOverlayText.
+func WithCastOverlayText(text string, pos Corner) CastOption {
+	return func(t *ChromecastTarget) {
+		t.addText(text, pos)
+	}
+}
+
+// WithCastOverlayImage draws an image in a corner of each frame, like WithOverlayImage.
+func WithCastOverlayImage(img image.Image, pos Corner) CastOption {
+	return func(t *ChromecastTarget) {
+		t.addImage(img, pos)
+	}
+}
+
+// NewChromecastTarget creates a target that displays images on a Chromecast.
+// The device connection is opened on the first Update and reopened after failures.
+func NewChromecastTarget(device ChromecastDevice, opts ...CastOption) (*ChromecastTarget, error) {
+	if device.Host == "" {
+		return nil, fmt.Errorf("chromecast host is required")
+	}
+	if device.Port == 0 {
+		device.Port = defaultCastPort
+	}
+
+	target := &ChromecastTarget{
+		device:      device,
+		dialTimeout: 10 * time.Second,
+		spriteOpts: sprites.Options{
+			Width:     1920,
+			Height:    1080,
+			FrameRate: 30,
+			UseGPU:    false, // Use software rendering for headless
+		},
+	}
+
+	for _, opt := range opts {
+		opt(target)
+	}
+
+	if target.localIP == "" {
+		target.localIP = getLocalIP()
+	}
+
+	// Create sprite renderer, unless a shared one was given
+	spriteOpts, err := target.initSprites(target.spriteOpts)
+	if err != nil {
+		return nil, err
+	}
+	target.spriteOpts = spriteOpts
+
+	// Serve frames on an ephemeral port
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		target.closeSprites()
+		return nil, fmt.Errorf("listen for frame server: %w", err)
+	}
+	target.listener = listener
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/frame/", target.serveFrame)
+	target.httpServer = &http.Server{Handler: mux}
+	go target.httpServer.Serve(listener)
+
+	return target, nil
+}
+
+// Name implements Target.
+func (t *ChromecastTarget) Name() string {
+	if t.device.Name == "" {
+		return "Chromecast"
+	}
+	return fmt.Sprintf("Chromecast(%s)", t.device.Name)
+}
+
+// Update implements Target.
+func (t *ChromecastTarget) Update(ctx context.Context, state *ViewState) error {
+	// Convert ViewState to sprites.State
+	adapter := NewSpritesStateAdapter(state)
+
+	// Render frame
+	frame := t.sprites.Render(adapter)
+	if frame == nil {
+		return fmt.Errorf("failed to render frame")
+	}
+	return t.updateImage(ctx, frame, failedCells(state, t.spriteOpts))
+}
+
+// UpdateImage implements ImageTarget.
+func (t *ChromecastTarget) UpdateImage(ctx context.Context, frame image.Image) error {
+	return t.updateImage(ctx, frame, nil)
+}
+
+// updateImage implements stateImageTarget.
+func (t *ChromecastTarget) updateImage(ctx context.Context, frame image.Image, failed []image.Rectangle) error {
+	frame = t.recolor(frame, failed)
+
+	// Crop to the configured grid region
+	if t.viewport != nil {
+		rect := gridRectToPixels(*t.viewport, t.spriteOpts)
+		if !rect.Overlaps(frame.Bounds()) {
+			return fmt.Errorf("viewport %+v is outside the rendered frame", *t.viewport)
+		}
+		frame = cropImage(frame, rect)
+	}
+	t.markUpdated()
+	frame = t.drawOverlays(frame, time.Now())
+
+	jpegData, err := encodeJPEG(frame, 0)
+	if err != nil {
+		return fmt.Errorf("convert to JPEG: %w", err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Skip if image hasn't changed
+	unchanged := bytes.Equal(jpegData, t.lastImageBytes)
+	t.recordFrame(len(jpegData), unchanged)
+	if unchanged {
+		return nil
+	}
+
+	if err := t.ensureSession(ctx); err != nil {
+		t.sendErr = fmt.Errorf("connect to %s: %w", t.device.Name, err)
+		return t.sendErr
+	}
+
+	// A new URL per frame makes the receiver fetch it instead of reusing its cached copy
+	t.seq++
+	t.frame = jpegData
+	port := t.listener.Addr().(*net.TCPAddr).Port
+	url := fmt.Sprintf("http://%s:%d/frame/%d.jpg", t.localIP, port, t.seq)
+
+	if err := t.conn.loadMedia(t.transportID, url, "image/jpeg"); err != nil {
+		t.resetSession()
+		t.sendErr = fmt.Errorf("load image on %s: %w", t.device.Name, err)
+		return t.sendErr
+	}
+	t.lastImageBytes = jpegData // Only after success, so a failed frame is retried
+	t.sendErr = nil
+	return nil
+}
+
+// Health implements HealthChecker by reporting whether the most recent image
+// reached the device. It is healthy before the first send.
+func (t *ChromecastTarget) Health() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sendErr
+}
+
+// Validate implements Validator by connecting to the device without launching
+// the media receiver, so nothing is displayed.
+func (t *ChromecastTarget) Validate(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, t.dialTimeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(t.device.Host, strconv.Itoa(t.device.Port))
+	conn, err := dialCast(ctx, addr)
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w", addr, err)
+	}
+	return conn.Close()
+}
+
+// ensureSession connects to the device and launches the media receiver if needed.
+// Must be called with t.mu held.
+func (t *ChromecastTarget) ensureSession(ctx context.Context) error {
+	if t.conn != nil && t.conn.alive() {
+		return nil
+	}
+	t.resetSession()
+
+	ctx, cancel := context.WithTimeout(ctx, t.dialTimeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(t.device.Host, strconv.Itoa(t.device.Port))
+	conn, err := dialCast(ctx, addr)
+	if err != nil {
+		return err
+	}
+	transportID, err := conn.launchMediaReceiver(ctx)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("launch media receiver: %w", err)
+	}
+
+	t.conn = conn
+	t.transportID = transportID
+	return nil
+}
+
+// resetSession drops the device connection so the next Update reconnects.
+// Must be called with t.mu held.
+func (t *ChromecastTarget) resetSession() {
+	if t.conn != nil {
+		t.conn.Close()
+	}
+	t.conn = nil
+	t.transportID = ""
+}
+
+// serveFrame serves the most recent frame regardless of the sequence number in the URL.
+func (t *ChromecastTarget) serveFrame(w http.ResponseWriter, r *http.Request) {
+	t.mu.Lock()
+	data := t.frame
+	t.mu.Unlock()
+
+	if data == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Write(data)
+}
+
+// Close implements Target.
+// Closing more than once is a no-op.
+func (t *ChromecastTarget) Close() error {
+	t.closeOnce.Do(func() {
+		t.mu.Lock()
+		t.resetSession()
+		t.mu.Unlock()
+
+		if t.httpServer != nil {
+			t.httpServer.Close()
+		}
+		t.closeSprites()
+	})
+	return nil
+}
@@ -0,0 +1,78 @@
+package nimsforestviewer
+
+import (
+	"context"
+	"fmt"
+)
+
+// FilterTarget forwards only the lands matching a predicate to an inner target,
+// e.g. to show just manalands on a TV while the web view shows everything.
+type FilterTarget struct {
+	inner Target
+	pred  func(LandView) bool
+}
+
+// NewFilterTarget wraps inner so it only receives lands for which pred returns true.
+// The summary is recomputed for the filtered lands.
+func NewFilterTarget(inner Target, pred func(LandView) bool) *FilterTarget {
+	return &FilterTarget{inner: inner, pred: pred}
+}
+
+// Name implements Target.
+func (t *FilterTarget) Name() string {
+	return fmt.Sprintf("Filter(%s)", t.inner.Name())
+}
+
+// Update implements Target.
+// The incoming state is shared with other targets and is never modified;
+// the inner target receives a new ViewState.
+func (t *FilterTarget) Update(ctx context.Context, state *ViewState) error {
+	if state == nil {
+		return t.inner.Update(ctx, nil)
+	}
+
+	filtered := &ViewState{}
+	for _, land := range state.Lands {
+		if t.pred(land) {
+			filtered.Lands = append(filtered.Lands, land.Clone())
+		}
+	}
+	filtered.RecomputeSummary()
+
+	return t.inner.Update(ctx, filtered)
+}
+
+// SetTheme implements ThemedTarget by passing the theme to the inner target, if it is themed.
+func (t *FilterTarget) SetTheme(theme Theme) {
+	if inner, ok := t.inner.(ThemedTarget); ok {
+		inner.SetTheme(theme)
+	}
+}
+
+// SetLogger implements LoggingTarget by passing the logger to the inner target, if it logs.
+func (t *FilterTarget) SetLogger(l Logger) {
+	if inner, ok := t.inner.(LoggingTarget); ok {
+		inner.SetLogger(l)
+	}
+}
+
+// Health implements HealthChecker by reporting the inner target's health, if it has one.
+func (t *FilterTarget) Health() error {
+	if inner, ok := t.inner.(HealthChecker); ok {
+		return inner.Health()
+	}
+	return nil
+}
+
+// Validate implements Validator by validating the inner target, if it can be.
+func (t *FilterTarget) Validate(ctx context.Context) error {
+	if inner, ok := t.inner.(Validator); ok {
+		return inner.Validate(ctx)
+	}
+	return nil
+}
+
+// Close implements Target.
+func (t *FilterTarget) Close() error {
+	return t.inner.Close()
+}
@@ -0,0 +1,321 @@
+//go:build linux
+
+package nimsforestviewer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	sprites "github.com/nimsforest/nimsforestsprites"
+)
+
+// Framebuffer ioctl requests from linux/fb.h.
+const (
+	fbioGetVScreenInfo = 0x4600
+	fbioGetFScreenInfo = 0x4602
+)
+
+// fbBitfield mirrors struct fb_bitfield.
+type fbBitfield struct {
+	Offset   uint32
+	Length   uint32
+	MSBRight uint32
+}
+
+// fbVarScreenInfo mirrors struct fb_var_screeninfo.
+type fbVarScreenInfo struct {
+	XRes, YRes               uint32
+	XResVirtual, YResVirtual uint32
+	XOffset, YOffset         uint32
+	BitsPerPixel             uint32
+	Grayscale                uint32
+	Red, Green, Blue, Transp fbBitfield
+	NonStd                   uint32
+	Activate                 uint32
+	Height, Width            uint32
+	AccelFlags               uint32
+	PixClock                 uint32
+	LeftMargin, RightMargin  uint32
+	UpperMargin, LowerMargin uint32
+	HSyncLen, VSyncLen       uint32
+	Sync, VMode, Rotate      uint32
+	Colorspace               uint32
+	Reserved                 [4]uint32
+}
+
+// fbFixScreenInfo mirrors struct fb_fix_screeninfo.
+type fbFixScreenInfo struct {
+	ID           [16]byte
+	SMemStart    uintptr
+	SMemLen      uint32
+	Type         uint32
+	TypeAux      uint32
+	Visual       uint32
+	XPanStep     uint16
+	YPanStep     uint16
+	YWrapStep    uint16
+	LineLength   uint32
+	MMIOStart    uintptr
+	MMIOLen      uint32
+	Accel        uint32
+	Capabilities uint16
+	Reserved     [2]uint16
+}
+
+// FramebufferTarget draws the sprite frame directly on a Linux framebuffer device,
+// for kiosks such as a Raspberry Pi driving a panel without X or Wayland.
+type FramebufferTarget struct {
+	device     string
+	file       *os.File
+	mem        []byte // Memory-mapped framebuffer
+	vinfo      fbVarScreenInfo
+	finfo      fbFixScreenInfo
+	spriteOpts sprites.Options
+	viewport   *Viewport
+	lastPix    []byte // Cache to avoid redundant blits
+	mu         sync.Mutex
+	closeOnce  sync.Once
+	spriteSource
+	themer
+	overlays
+	frameStats
+}
+
+// FramebufferOption configures a FramebufferTarget.
+type FramebufferOption func(*FramebufferTarget)
+
+// WithFramebufferSpriteOptions sets the sprite renderer options.
+// A zero Width or Height defaults to the framebuffer's visible resolution.
+func WithFramebufferSpriteOptions(opts sprites.Options) FramebufferOption {
+	return func(t *FramebufferTarget) {
+		t.spriteOpts = opts
+	}
+}
+
+// WithFramebufferSharedRenderer renders frames through r instead of a renderer of the
+// target's own. The renderer's options replace WithFramebufferSpriteOptions.
+func WithFramebufferSharedRenderer(r *SharedRenderer) FramebufferOption {
+	return func(t *FramebufferTarget) {
+		t.sprites = r
+	}
+}
+
+// WithFramebufferViewport crops the rendered frame to a region of the land grid, like WithViewport.
+func WithFramebufferViewport(x, y, w, h int) FramebufferOption {
+	return func(t *FramebufferTarget) {
+		t.viewport = &Viewport{X: x, Y: y, Width: w, Height: h}
+	}
+}
+
+// WithFramebufferTimestampOverlay draws the current time on each frame, like WithTimestampOverlay.
+func WithFramebufferTimestampOverlay(enable bool) FramebufferOption {
+	return func(t *FramebufferTarget) {
+		t.timestamp = enable
+	}
+}
+
+// WithFramebufferOverlayText draws a text banner in a corner of each frame, like WithOverlayText.
+func WithFramebufferOverlayText(text string, pos Corner) FramebufferOption {
+	return func(t *FramebufferTarget) {
+		t.addText(text, pos)
+	}
+}
+
+// WithFramebufferOverlayImage draws an image in a corner of each frame, like WithOverlayImage.
+func WithFramebufferOverlayImage(img image.Image, pos Corner) FramebufferOption {
+	return func(t *FramebufferTarget) {
+		t.addImage(img, pos)
+	}
+}
+
+// NewFramebufferTarget creates a target that draws on a framebuffer device such as "/dev/fb0".
+// The device geometry and pixel format are queried via ioctl.
+func NewFramebufferTarget(device string, opts ...FramebufferOption) (*FramebufferTarget, error) {
+	target := &FramebufferTarget{
+		device: device,
+		spriteOpts: sprites.Options{
+			FrameRate: 30,
+			UseGPU:    false, // Use software rendering for headless
+		},
+	}
+
+	for _, opt := range opts {
+		opt(target)
+	}
+
+	file, err := os.OpenFile(device, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open framebuffer: %w", err)
+	}
+	target.file = file
+
+	if err := fbIoctl(file, fbioGetVScreenInfo, unsafe.Pointer(&target.vinfo)); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("query variable screen info: %w", err)
+	}
+	if err := fbIoctl(file, fbioGetFScreenInfo, unsafe.Pointer(&target.finfo)); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("query fixed screen info: %w", err)
+	}
+
+	switch target.vinfo.BitsPerPixel {
+	case 16, 24, 32:
+	default:
+		file.Close()
+		return nil, fmt.Errorf("unsupported pixel depth %d bpp", target.vinfo.BitsPerPixel)
+	}
+
+	mem, err := syscall.Mmap(int(file.Fd()), 0, int(target.finfo.SMemLen), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("map framebuffer: %w", err)
+	}
+	target.mem = mem
+
+	if target.spriteOpts.Width == 0 {
+		target.spriteOpts.Width = int(target.vinfo.XRes)
+	}
+	if target.spriteOpts.Height == 0 {
+		target.spriteOpts.Height = int(target.vinfo.YRes)
+	}
+
+	// Create sprite renderer, unless a shared one was given
+	spriteOpts, err := target.initSprites(target.spriteOpts)
+	if err != nil {
+		target.release()
+		return nil, err
+	}
+	target.spriteOpts = spriteOpts
+
+	return target, nil
+}
+
+// Name implements Target.
+func (t *FramebufferTarget) Name() string {
+	return fmt.Sprintf("Framebuffer(%s)", t.device)
+}
+
+// Update implements Target.
+func (t *FramebufferTarget) Update(ctx context.Context, state *ViewState) error {
+	// Convert ViewState to sprites.State
+	adapter := NewSpritesStateAdapter(state)
+
+	// Render frame
+	frame := t.sprites.Render(adapter)
+	if frame == nil {
+		return fmt.Errorf("failed to render frame")
+	}
+	return t.updateImage(ctx, frame, failedCells(state, t.spriteOpts))
+}
+
+// UpdateImage implements ImageTarget.
+func (t *FramebufferTarget) UpdateImage(ctx context.Context, frame image.Image) error {
+	return t.updateImage(ctx, frame, nil)
+}
+
+// updateImage implements stateImageTarget.
+func (t *FramebufferTarget) updateImage(ctx context.Context, frame image.Image, failed []image.Rectangle) error {
+	frame = t.recolor(frame, failed)
+
+	// Crop to the configured grid region
+	if t.viewport != nil {
+		rect := gridRectToPixels(*t.viewport, t.spriteOpts)
+		if !rect.Overlaps(frame.Bounds()) {
+			return fmt.Errorf("viewport %+v is outside the rendered frame", *t.viewport)
+		}
+		frame = cropImage(frame, rect)
+	}
+	t.markUpdated()
+	rgba := ensureRGBA(t.drawOverlays(frame, time.Now()))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Skip if image hasn't changed
+	unchanged := bytes.Equal(rgba.Pix, t.lastPix)
+	t.recordFrame(len(rgba.Pix), unchanged)
+	if unchanged {
+		return nil
+	}
+	t.lastPix = append(t.lastPix[:0], rgba.Pix...)
+
+	t.blit(rgba.Pix, rgba.Stride, rgba.Rect.Dx(), rgba.Rect.Dy())
+	return nil
+}
+
+// blit converts RGBA pixels to the device format and copies them into the
+// visible area, clipping anything outside the screen.
+func (t *FramebufferTarget) blit(pix []byte, stride, width, height int) {
+	v := t.vinfo
+	bpp := int(v.BitsPerPixel) / 8
+	lineLength := int(t.finfo.LineLength)
+	width = min(width, int(v.XRes))
+	height = min(height, int(v.YRes))
+
+	for y := 0; y < height; y++ {
+		src := pix[y*stride:]
+		dstRow := (int(v.YOffset)+y)*lineLength + int(v.XOffset)*bpp
+		if dstRow+width*bpp > len(t.mem) {
+			return
+		}
+		dst := t.mem[dstRow:]
+		for x := 0; x < width; x++ {
+			s := src[x*4 : x*4+4]
+			px := fbChannel(s[0], v.Red) | fbChannel(s[1], v.Green) | fbChannel(s[2], v.Blue) | fbChannel(s[3], v.Transp)
+			// Framebuffer pixels are stored in native (little-endian on the Pi) byte order
+			for i := 0; i < bpp; i++ {
+				dst[x*bpp+i] = byte(px >> (8 * i))
+			}
+		}
+	}
+}
+
+// fbChannel scales an 8-bit channel value into its bitfield position.
+func fbChannel(c byte, f fbBitfield) uint32 {
+	if f.Length == 0 {
+		return 0
+	}
+	if f.Length < 8 {
+		return uint32(c>>(8-f.Length)) << f.Offset
+	}
+	return uint32(c) << f.Offset
+}
+
+// Close implements Target.
+// It leaves the last frame on screen. Closing more than once is a no-op.
+func (t *FramebufferTarget) Close() error {
+	t.closeOnce.Do(func() {
+		t.closeSprites()
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.release()
+	})
+	return nil
+}
+
+// release unmaps and closes the device.
+func (t *FramebufferTarget) release() {
+	if t.mem != nil {
+		syscall.Munmap(t.mem)
+		t.mem = nil
+	}
+	if t.file != nil {
+		t.file.Close()
+		t.file = nil
+	}
+}
+
+func fbIoctl(f *os.File, req uintptr, arg unsafe.Pointer) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), req, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
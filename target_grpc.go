@@ -0,0 +1,337 @@
+package nimsforestviewer
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// grpcWatchMethod is the HTTP/2 path of ViewerService.WatchViewModel in proto/viewer.proto.
+const grpcWatchMethod = "/nimsforest.viewer.v1.ViewerService/WatchViewModel"
+
+// gRPC status codes used by GRPCTarget.
+const (
+	grpcStatusOK            = 0
+	grpcStatusUnimplemented = 12
+	grpcStatusUnavailable   = 14
+)
+
+// GRPCTarget serves the ViewerService defined in proto/viewer.proto.
+// Clients call WatchViewModel and receive the current World followed by one
+// message per Update. Clients generate their stubs from the proto file; the
+// server side encodes messages directly and runs on net/http's HTTP/2 support,
+// so the package doesn't depend on the gRPC runtime.
+type GRPCTarget struct {
+	addr       string
+	bufferSize int
+	listener   net.Listener
+	server     *http.Server
+
+	mu          sync.Mutex
+	latest      []byte // Last encoded World, sent to new subscribers
+	subscribers map[*grpcSubscriber]struct{}
+	closed      bool
+	closeOnce   sync.Once
+}
+
+// grpcSubscriber is one WatchViewModel stream.
+type grpcSubscriber struct {
+	ch chan []byte
+}
+
+// GRPCOption configures a GRPCTarget.
+type GRPCOption func(*GRPCTarget)
+
+// WithGRPCAddr sets the listen address. Defaults to ":50051".
+func WithGRPCAddr(addr string) GRPCOption {
+	return func(t *GRPCTarget) {
+		t.addr = addr
+	}
+}
+
+// WithGRPCBufferSize sets how many updates are queued per subscriber.
+// When a slow subscriber's buffer is full, its oldest queued update is dropped.
+// Defaults to 8.
+func WithGRPCBufferSize(n int) GRPCOption {
+	return func(t *GRPCTarget) {
+		if n > 0 {
+			t.bufferSize = n
+		}
+	}
+}
+
+// NewGRPCTarget creates a target and starts its gRPC server.
+// The server speaks HTTP/2 without TLS (h2c), as gRPC clients do by default with insecure credentials.
+func NewGRPCTarget(opts ...GRPCOption) (*GRPCTarget, error) {
+	target := &GRPCTarget{
+		addr:        ":50051",
+		bufferSize:  8,
+		subscribers: make(map[*grpcSubscriber]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(target)
+	}
+
+	listener, err := net.Listen("tcp", target.addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", target.addr, err)
+	}
+	target.listener = listener
+
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	target.server = &http.Server{
+		Handler:   http.HandlerFunc(target.serveGRPC),
+		Protocols: &protocols,
+	}
+	go target.server.Serve(listener)
+
+	return target, nil
+}
+
+// Name implements Target.
+func (t *GRPCTarget) Name() string {
+	return fmt.Sprintf("gRPC(%s)", t.addr)
+}
+
+// Addr returns the address the server is listening on.
+func (t *GRPCTarget) Addr() net.Addr {
+	return t.listener.Addr()
+}
+
+// Update implements Target.
+// It encodes the state once and queues it for every subscriber.
+func (t *GRPCTarget) Update(ctx context.Context, state *ViewState) error {
+	msg := marshalWorldProto(ViewStateToJSON(state))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.latest = msg
+	for sub := range t.subscribers {
+		sub.send(msg)
+	}
+	return nil
+}
+
+// send queues msg, dropping the oldest queued message if the buffer is full.
+// Callers hold GRPCTarget.mu, so sends to one subscriber never race.
+func (s *grpcSubscriber) send(msg []byte) {
+	for {
+		select {
+		case s.ch <- msg:
+			return
+		default:
+		}
+		select {
+		case <-s.ch:
+		default:
+		}
+	}
+}
+
+// subscribe registers a new stream and returns it with the current state, if any.
+func (t *GRPCTarget) subscribe() (*grpcSubscriber, []byte, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil, nil, false
+	}
+	sub := &grpcSubscriber{ch: make(chan []byte, t.bufferSize)}
+	t.subscribers[sub] = struct{}{}
+	return sub, t.latest, true
+}
+
+func (t *GRPCTarget) unsubscribe(sub *grpcSubscriber) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.subscribers[sub]; ok {
+		delete(t.subscribers, sub)
+		close(sub.ch)
+	}
+}
+
+// serveGRPC handles gRPC requests. Only WatchViewModel is implemented.
+func (t *GRPCTarget) serveGRPC(w http.ResponseWriter, r *http.Request) {
+	if r.ProtoMajor != 2 || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "gRPC requires HTTP/2 with content-type application/grpc", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+
+	if r.Method != http.MethodPost || r.URL.Path != grpcWatchMethod {
+		grpcTrailer(w, grpcStatusUnimplemented, "unknown method "+r.URL.Path)
+		return
+	}
+
+	// The request message is empty; drain it so the stream is half-closed cleanly
+	io.Copy(io.Discard, r.Body)
+
+	sub, latest, ok := t.subscribe()
+	if !ok {
+		grpcTrailer(w, grpcStatusUnavailable, "server is shutting down")
+		return
+	}
+	defer t.unsubscribe(sub)
+
+	rc := http.NewResponseController(w)
+	w.WriteHeader(http.StatusOK)
+	if latest != nil {
+		if err := writeGRPCMessage(w, latest); err != nil {
+			return
+		}
+	}
+	rc.Flush()
+
+	for {
+		select {
+		case msg, ok := <-sub.ch:
+			if !ok {
+				// Target closed: end the stream normally
+				grpcTrailer(w, grpcStatusOK, "")
+				return
+			}
+			if err := writeGRPCMessage(w, msg); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeGRPCMessage writes one length-prefixed, uncompressed gRPC message.
+func writeGRPCMessage(w io.Writer, msg []byte) error {
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	_, err := w.Write(append(frame, msg...))
+	return err
+}
+
+// grpcTrailer sets the status trailers that end a gRPC response.
+func grpcTrailer(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Grpc-Status", fmt.Sprint(code))
+	if msg != "" {
+		w.Header().Set("Grpc-Message", msg)
+	}
+}
+
+// Close implements Target.
+// Open streams end with status OK before the server shuts down. Closing more than once is a no-op.
+func (t *GRPCTarget) Close() error {
+	var err error
+	t.closeOnce.Do(func() {
+		t.mu.Lock()
+		t.closed = true
+		for sub := range t.subscribers {
+			delete(t.subscribers, sub)
+			close(sub.ch)
+		}
+		t.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err = t.server.Shutdown(ctx)
+	})
+	return err
+}
+
+// marshalWorldProto encodes a World message from proto/viewer.proto.
+func marshalWorldProto(world WorldJSON) []byte {
+	var b []byte
+	for _, land := range world.Lands {
+		b = appendProtoMessage(b, 1, marshalLandProto(land))
+	}
+
+	s := world.Summary
+	var sum []byte
+	sum = appendProto3Int(sum, 1, s.LandCount)
+	sum = appendProto3Int(sum, 2, s.ManalandCount)
+	sum = appendProto3Int(sum, 3, s.TreeCount)
+	sum = appendProto3Int(sum, 4, s.TreehouseCount)
+	sum = appendProto3Int(sum, 5, s.NimCount)
+	sum = appendProto3Uint(sum, 6, s.TotalRAM)
+	sum = appendProto3Uint(sum, 7, s.RAMAllocated)
+	sum = appendProto3Double(sum, 8, s.Occupancy)
+	sum = appendProto3String(sum, 9, s.TotalRAMHuman)
+	sum = appendProto3String(sum, 10, s.RAMAllocatedHuman)
+	b = appendProtoMessage(b, 2, sum)
+
+	for _, e := range world.Edges {
+		var edge []byte
+		edge = appendProto3String(edge, 1, e.Subject)
+		edge = appendProto3String(edge, 2, e.From)
+		edge = appendProto3String(edge, 3, e.FromLand)
+		edge = appendProto3String(edge, 4, e.To)
+		edge = appendProto3String(edge, 5, e.ToLand)
+		b = appendProtoMessage(b, 3, edge)
+	}
+
+	b = appendProto3Int(b, 4, world.Version)
+	if !world.GeneratedAt.IsZero() {
+		var ts []byte // google.protobuf.Timestamp
+		ts = appendProto3Int(ts, 1, int(world.GeneratedAt.Unix()))
+		ts = appendProto3Int(ts, 2, world.GeneratedAt.Nanosecond())
+		b = appendProtoMessage(b, 5, ts)
+	}
+	return b
+}
+
+func marshalLandProto(land LandJSON) []byte {
+	var b []byte
+	b = appendProto3String(b, 1, land.ID)
+	b = appendProto3String(b, 2, land.Hostname)
+	b = appendProto3Uint(b, 3, land.RAMTotal)
+	b = appendProto3Uint(b, 4, land.RAMAllocated)
+	b = appendProto3Int(b, 5, land.CPUCores)
+	b = appendProto3Double(b, 6, land.CPUFreqGHz)
+	b = appendProto3Uint(b, 7, land.GPUVram)
+	b = appendProto3Double(b, 8, land.GPUTflops)
+	b = appendProto3Double(b, 9, land.Occupancy)
+	b = appendProto3Bool(b, 10, land.IsManaland)
+	b = appendProto3Int(b, 11, land.GridX)
+	b = appendProto3Int(b, 12, land.GridY)
+	for _, p := range land.Trees {
+		b = appendProtoMessage(b, 13, marshalProcessProto(p))
+	}
+	for _, p := range land.Treehouses {
+		b = appendProtoMessage(b, 14, marshalProcessProto(p))
+	}
+	for _, p := range land.Nims {
+		b = appendProtoMessage(b, 15, marshalProcessProto(p))
+	}
+	b = appendProto3String(b, 16, land.Status)
+	b = appendProto3Int(b, 17, land.ProcessCount)
+	b = appendProto3Int(b, 18, land.HiddenProcesses)
+	return b
+}
+
+func marshalProcessProto(p ProcessJSON) []byte {
+	var b []byte
+	b = appendProto3String(b, 1, p.ID)
+	b = appendProto3String(b, 2, p.Name)
+	b = appendProto3Uint(b, 3, p.RAMAllocated)
+	b = appendProto3String(b, 4, p.Type)
+	b = appendProto3Double(b, 5, p.Progress)
+	for _, s := range p.Subjects {
+		b = appendProtoString(b, 6, s)
+	}
+	b = appendProto3String(b, 7, p.ScriptPath)
+	b = appendProto3Bool(b, 8, p.AIEnabled)
+	b = appendProto3String(b, 9, p.Model)
+	b = appendProto3String(b, 10, p.State)
+	b = appendProto3String(b, 11, p.ParentID)
+	return b
+}
@@ -0,0 +1,208 @@
+package nimsforestviewer
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// protoField is a field declared in proto/viewer.proto.
+type protoField struct {
+	name     string
+	typ      string // Scalar type or message name
+	repeated bool
+}
+
+var (
+	protoMessageRE = regexp.MustCompile(`message (\w+) \{([^}]*)\}`)
+	protoFieldRE   = regexp.MustCompile(`(?m)^\s*(repeated )?([\w.]+) (\w+) = (\d+);`)
+	protoScalars   = map[string]bool{"string": true, "uint64": true, "int32": true, "int64": true, "double": true, "bool": true}
+
+	// jsonOnlyFields are JSON fields deliberately left out of viewer.proto, by message.
+	jsonOnlyFields = map[string]map[string]bool{
+		// Staleness only matters to WebTarget's polling clients; gRPC streams push every update
+		"Summary": {"last_update": true, "stale_after": true},
+	}
+)
+
+// readProtoSchema returns the fields of every message in proto/viewer.proto by number.
+func readProtoSchema(t *testing.T) map[string]map[uint64]protoField {
+	t.Helper()
+	src, err := os.ReadFile("proto/viewer.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := make(map[string]map[uint64]protoField)
+	for _, m := range protoMessageRE.FindAllStringSubmatch(string(src), -1) {
+		fields := make(map[uint64]protoField)
+		for _, f := range protoFieldRE.FindAllStringSubmatch(m[2], -1) {
+			num, _ := strconv.ParseUint(f[4], 10, 64)
+			fields[num] = protoField{name: f[3], typ: f[2], repeated: f[1] != ""}
+		}
+		schema[m[1]] = fields
+	}
+	schema["google.protobuf.Timestamp"] = map[uint64]protoField{
+		1: {name: "seconds", typ: "int64"},
+		2: {name: "nanos", typ: "int32"},
+	}
+	return schema
+}
+
+// decodeProto decodes msg as the named message, keyed by field name, with the
+// values JSON decoding would produce: numbers as float64, repeated fields as
+// []any and embedded messages as map[string]any.
+func decodeProto(t *testing.T, schema map[string]map[uint64]protoField, name string, msg []byte) map[string]any {
+	t.Helper()
+	fields, ok := schema[name]
+	if !ok {
+		t.Fatalf("message %s not in viewer.proto", name)
+	}
+	out := make(map[string]any)
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		if n <= 0 {
+			t.Fatalf("%s: bad field key", name)
+		}
+		msg = msg[n:]
+		field, ok := fields[key>>3]
+		if !ok {
+			t.Fatalf("%s: field number %d not in viewer.proto", name, key>>3)
+		}
+
+		var value any
+		switch wire := key & 7; {
+		case wire == 0 && (field.typ == "uint64" || field.typ == "int32" || field.typ == "int64"):
+			v, n := binary.Uvarint(msg)
+			msg = msg[n:]
+			if field.typ != "uint64" {
+				value = float64(int64(v))
+			} else {
+				value = float64(v)
+			}
+		case wire == 0 && field.typ == "bool":
+			v, n := binary.Uvarint(msg)
+			msg = msg[n:]
+			value = v != 0
+		case wire == 1 && field.typ == "double":
+			value = math.Float64frombits(binary.LittleEndian.Uint64(msg))
+			msg = msg[8:]
+		case wire == 2:
+			size, n := binary.Uvarint(msg)
+			data := msg[n : n+int(size)]
+			msg = msg[n+int(size):]
+			if field.typ == "string" {
+				value = string(data)
+			} else {
+				value = decodeProto(t, schema, field.typ, data)
+			}
+		default:
+			t.Fatalf("%s.%s: wire type %d doesn't match declared type %s", name, field.name, wire, field.typ)
+		}
+
+		if field.repeated {
+			list, _ := out[field.name].([]any)
+			out[field.name] = append(list, value)
+		} else {
+			out[field.name] = value
+		}
+	}
+	return out
+}
+
+// TestWorldProtoMatchesSchema checks marshalWorldProto against proto/viewer.proto:
+// every declared field must be encoded under its number with the wire type of
+// its declared type, and hold the value the JSON field of the same name has.
+// Every JSON field must in turn be declared, unless listed in jsonOnlyFields.
+func TestWorldProtoMatchesSchema(t *testing.T) {
+	process := ProcessJSON{
+		ID: "p1", Name: "tree", RAMAllocated: 1 << 30, Type: "tree", Progress: 0.5,
+		State: ProcessStateFailed, Subjects: []string{"a", "b"}, ParentID: "p0",
+		ScriptPath: "run.sh", AIEnabled: true, Model: "model",
+	}
+	world := WorldJSON{
+		Version:     SchemaVersion,
+		GeneratedAt: time.Date(2026, 1, 2, 3, 4, 5, 6000, time.UTC),
+		Lands: []LandJSON{{
+			ID: "land-1", Hostname: "host", RAMTotal: 16 << 30, RAMAllocated: 4 << 30,
+			CPUCores: 8, CPUFreqGHz: 3.2, GPUVram: 8 << 30, GPUTflops: 10.5, Occupancy: 0.25,
+			Status: LandStatusWarn, IsManaland: true, GridX: 2, GridY: 3, ProcessCount: 12, HiddenProcesses: 10,
+			Trees: []ProcessJSON{process}, Treehouses: []ProcessJSON{process}, Nims: []ProcessJSON{process},
+		}},
+		Edges: []EdgeJSON{{Subject: "s", From: "p1", FromLand: "land-1", To: "p2", ToLand: "land-2"}},
+		Summary: SummaryJSON{
+			LandCount: 1, ManalandCount: 1, TreeCount: 1, TreehouseCount: 1, NimCount: 1,
+			TotalRAM: 16 << 30, RAMAllocated: 4 << 30, Occupancy: 0.25,
+			TotalRAMHuman: "16.0 GiB", RAMAllocatedHuman: "4.0 GiB",
+			LastUpdate: time.Date(2026, 1, 2, 3, 4, 0, 0, time.UTC), StaleAfter: 30,
+		},
+	}
+
+	schema := readProtoSchema(t)
+	got := decodeProto(t, schema, "World", marshalWorldProto(world))
+
+	data, err := json.Marshal(world)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want map[string]any
+	if err := json.Unmarshal(data, &want); err != nil {
+		t.Fatal(err)
+	}
+	compareProtoToJSON(t, schema, "World", got, want)
+}
+
+// compareProtoToJSON checks that every field of the named message is present in
+// got and equals the JSON field of the same name in want, and that every field
+// in want is declared in the message.
+func compareProtoToJSON(t *testing.T, schema map[string]map[uint64]protoField, name string, got, want map[string]any) {
+	t.Helper()
+	declared := make(map[string]bool)
+	for _, field := range schema[name] {
+		declared[field.name] = true
+	}
+	for key := range want {
+		if !declared[key] && !jsonOnlyFields[name][key] {
+			t.Errorf("JSON field %s.%s not in viewer.proto", name, key)
+		}
+	}
+	for num, field := range schema[name] {
+		g, ok := got[field.name]
+		if !ok {
+			t.Errorf("%s.%s (field %d) not encoded", name, field.name, num)
+			continue
+		}
+		w := want[field.name]
+		if field.typ == "google.protobuf.Timestamp" {
+			ts := g.(map[string]any)
+			got := time.Unix(int64(ts["seconds"].(float64)), int64(ts["nanos"].(float64)))
+			if at, err := time.Parse(time.RFC3339Nano, w.(string)); err != nil || !got.Equal(at) {
+				t.Errorf("%s.%s (field %d) = %v, want %v", name, field.name, num, got, w)
+			}
+			continue
+		}
+		if protoScalars[field.typ] {
+			if !reflect.DeepEqual(g, w) {
+				t.Errorf("%s.%s (field %d) = %v, want %v", name, field.name, num, g, w)
+			}
+			continue
+		}
+		if !field.repeated {
+			compareProtoToJSON(t, schema, field.typ, g.(map[string]any), w.(map[string]any))
+			continue
+		}
+		gl, wl := g.([]any), w.([]any)
+		if len(gl) != len(wl) {
+			t.Errorf("%s.%s has %d entries, want %d", name, field.name, len(gl), len(wl))
+			continue
+		}
+		for i := range gl {
+			compareProtoToJSON(t, schema, field.typ, gl[i].(map[string]any), wl[i].(map[string]any))
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package nimsforestviewer
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MQTTTarget publishes the world JSON to an MQTT topic on every update,
+// so dashboards and other non-HTTP consumers can subscribe to live cluster state.
+type MQTTTarget struct {
+	brokerURL   string
+	topic       string
+	qos         byte
+	retain      bool
+	cfg         mqttConfig
+	dialTimeout time.Duration
+
+	mu     sync.Mutex
+	client *mqttClient
+	closed bool
+}
+
+// MQTTOption configures an MQTTTarget.
+type MQTTOption func(*MQTTTarget)
+
+// WithMQTTQoS sets the publish QoS level. Only 0 (at most once) and 1 (at least once)
+// are supported. Defaults to 0.
+func WithMQTTQoS(qos byte) MQTTOption {
+	return func(t *MQTTTarget) {
+		t.qos = qos
+	}
+}
+
+// WithMQTTRetain marks published messages as retained, so new subscribers
+// immediately receive the latest state.
+func WithMQTTRetain(retain bool) MQTTOption {
+	return func(t *MQTTTarget) {
+		t.retain = retain
+	}
+}
+
+// WithMQTTClientID sets the client identifier. Defaults to a random "nimsforestviewer-" ID.
+func WithMQTTClientID(id string) MQTTOption {
+	return func(t *MQTTTarget) {
+		t.cfg.clientID = id
+	}
+}
+
+// WithMQTTCredentials sets the user name and password sent when connecting.
+func WithMQTTCredentials(username, password string) MQTTOption {
+	return func(t *MQTTTarget) {
+		t.cfg.username = username
+		t.cfg.password = password
+	}
+}
+
+// WithMQTTKeepAlive sets the keep-alive interval. Defaults to 60s; 0 disables it.
+func WithMQTTKeepAlive(d time.Duration) MQTTOption {
+	return func(t *MQTTTarget) {
+		t.cfg.keepAlive = d
+	}
+}
+
+// WithMQTTTLSConfig sets the TLS configuration for ssl://, tls:// and mqtts:// brokers.
+func WithMQTTTLSConfig(cfg *tls.Config) MQTTOption {
+	return func(t *MQTTTarget) {
+		t.cfg.tlsConfig = cfg
+	}
+}
+
+// NewMQTTTarget creates a target that publishes to topic on the broker at brokerURL,
+// e.g. "tcp://localhost:1883" or "mqtts://broker.example.com".
+// The connection is established immediately and re-established on the next Update if it drops.
+func NewMQTTTarget(brokerURL, topic string, opts ...MQTTOption) (*MQTTTarget, error) {
+	if topic == "" {
+		return nil, fmt.Errorf("topic is required")
+	}
+
+	target := &MQTTTarget{
+		brokerURL:   brokerURL,
+		topic:       topic,
+		dialTimeout: 10 * time.Second,
+		cfg: mqttConfig{
+			keepAlive: 60 * time.Second,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(target)
+	}
+
+	if target.qos > 1 {
+		return nil, fmt.Errorf("unsupported QoS %d", target.qos)
+	}
+	if target.cfg.clientID == "" {
+		target.cfg.clientID = randomClientID()
+	}
+
+	if err := target.connect(context.Background()); err != nil {
+		return nil, fmt.Errorf("connect to %s: %w", brokerURL, err)
+	}
+
+	return target, nil
+}
+
+// Name implements Target.
+func (t *MQTTTarget) Name() string {
+	return fmt.Sprintf("MQTT(%s)", t.topic)
+}
+
+// Update implements Target.
+func (t *MQTTTarget) Update(ctx context.Context, state *ViewState) error {
+	payload, err := ViewStateToJSONBytes(state)
+	if err != nil {
+		return fmt.Errorf("marshal state: %w", err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return fmt.Errorf("target is closed")
+	}
+	if t.client == nil || !t.client.alive() {
+		if err := t.connect(ctx); err != nil {
+			return fmt.Errorf("reconnect to %s: %w", t.brokerURL, err)
+		}
+	}
+
+	if err := t.client.publish(ctx, t.topic, payload, t.qos, t.retain); err != nil {
+		return fmt.Errorf("publish to %s: %w", t.topic, err)
+	}
+	return nil
+}
+
+// connect replaces the current client with a fresh connection.
+func (t *MQTTTarget) connect(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, t.dialTimeout)
+	defer cancel()
+
+	client, err := dialMQTT(ctx, t.brokerURL, t.cfg)
+	if err != nil {
+		return err
+	}
+	t.client = client
+	return nil
+}
+
+// Close implements Target.
+// It disconnects from the broker cleanly. Closing more than once is a no-op.
+func (t *MQTTTarget) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+
+	if t.client == nil || !t.client.alive() {
+		return nil
+	}
+	return t.client.disconnect()
+}
+
+// randomClientID returns a client identifier that is unique per process.
+func randomClientID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return "nimsforestviewer-" + hex.EncodeToString(b)
+}
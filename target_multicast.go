@@ -0,0 +1,138 @@
+package nimsforestviewer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// MulticastPolicy decides how a MulticastTarget dispatches to its children.
+type MulticastPolicy int
+
+const (
+	// MulticastBroadcast updates every child and fails if any of them fails.
+	MulticastBroadcast MulticastPolicy = iota
+	// MulticastFailover updates children in order until one succeeds,
+	// e.g. a SmartTVTarget followed by a VideoTarget for the same TV.
+	MulticastFailover
+)
+
+// MulticastTarget groups several targets into one logical target.
+type MulticastTarget struct {
+	targets []Target
+	mu      sync.RWMutex
+	policy  MulticastPolicy
+}
+
+// NewMulticastTarget groups targets using MulticastBroadcast; see SetPolicy.
+func NewMulticastTarget(targets ...Target) *MulticastTarget {
+	return &MulticastTarget{targets: targets}
+}
+
+// SetPolicy sets how subsequent updates are dispatched.
+func (t *MulticastTarget) SetPolicy(policy MulticastPolicy) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.policy = policy
+}
+
+// Name implements Target.
+func (t *MulticastTarget) Name() string {
+	names := make([]string, len(t.targets))
+	for i, target := range t.targets {
+		names[i] = target.Name()
+	}
+	return fmt.Sprintf("Multicast(%s)", strings.Join(names, ", "))
+}
+
+// Update implements Target.
+// Children each get their own copy of the state.
+func (t *MulticastTarget) Update(ctx context.Context, state *ViewState) error {
+	t.mu.RLock()
+	policy := t.policy
+	t.mu.RUnlock()
+
+	var errs []error
+	for _, target := range t.targets {
+		err := target.Update(ctx, state.Clone())
+		if err == nil && policy == MulticastFailover {
+			return nil
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", target.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SetTheme implements ThemedTarget by passing the theme to every themed child.
+func (t *MulticastTarget) SetTheme(theme Theme) {
+	for _, target := range t.targets {
+		if themed, ok := target.(ThemedTarget); ok {
+			themed.SetTheme(theme)
+		}
+	}
+}
+
+// SetLogger implements LoggingTarget by passing the logger to every child that logs.
+func (t *MulticastTarget) SetLogger(l Logger) {
+	for _, target := range t.targets {
+		if logging, ok := target.(LoggingTarget); ok {
+			logging.SetLogger(l)
+		}
+	}
+}
+
+// Health implements HealthChecker. With MulticastBroadcast every child must be
+// healthy; with MulticastFailover one healthy child is enough.
+func (t *MulticastTarget) Health() error {
+	t.mu.RLock()
+	policy := t.policy
+	t.mu.RUnlock()
+
+	var errs []error
+	for _, target := range t.targets {
+		checker, ok := target.(HealthChecker)
+		if !ok {
+			if policy == MulticastFailover {
+				return nil
+			}
+			continue
+		}
+		err := checker.Health()
+		if err == nil && policy == MulticastFailover {
+			return nil
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", target.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate implements Validator. Every child is validated whatever the policy,
+// since a failover child that can't display is a misconfiguration too.
+func (t *MulticastTarget) Validate(ctx context.Context) error {
+	var errs []error
+	for _, target := range t.targets {
+		if validator, ok := target.(Validator); ok {
+			if err := validator.Validate(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", target.Name(), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close implements Target by closing every child.
+func (t *MulticastTarget) Close() error {
+	var errs []error
+	for _, target := range t.targets {
+		if err := target.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
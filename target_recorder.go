@@ -0,0 +1,112 @@
+package nimsforestviewer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// RecordJSON is one line of a recording written by RecorderTarget.
+type RecordJSON struct {
+	Timestamp time.Time `json:"timestamp"` // When the state was received
+	World     WorldJSON `json:"world"`
+}
+
+// RecorderTarget appends every state it receives to a file as JSON Lines, one
+// RecordJSON per line, giving an audit log of what was displayed. Recordings
+// can be played back with ReadRecording and a ReplayStateProvider.
+type RecorderTarget struct {
+	path   string
+	mu     sync.Mutex
+	file   *os.File
+	w      *bufio.Writer
+	closed bool
+}
+
+// NewRecorderTarget opens path for appending, creating it if needed.
+func NewRecorderTarget(path string) (*RecorderTarget, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open recording: %w", err)
+	}
+	return &RecorderTarget{path: path, file: file, w: bufio.NewWriter(file)}, nil
+}
+
+// Name implements Target.
+func (t *RecorderTarget) Name() string {
+	return fmt.Sprintf("Recorder(%s)", t.path)
+}
+
+// Update implements Target.
+// Each record is flushed to the file before Update returns, so a crash loses at
+// most the record being written.
+func (t *RecorderTarget) Update(ctx context.Context, state *ViewState) error {
+	line, err := json.Marshal(RecordJSON{Timestamp: time.Now(), World: ViewStateToJSON(state)})
+	if err != nil {
+		return fmt.Errorf("marshal state: %w", err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return fmt.Errorf("target is closed")
+	}
+	t.w.Write(line)
+	t.w.WriteByte('\n')
+	if err := t.w.Flush(); err != nil {
+		return fmt.Errorf("write recording: %w", err)
+	}
+	return nil
+}
+
+// Close implements Target by flushing and closing the file.
+// Closing more than once is a no-op.
+func (t *RecorderTarget) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+
+	flushErr := t.w.Flush()
+	closeErr := t.file.Close()
+	if flushErr != nil {
+		return fmt.Errorf("flush recording: %w", flushErr)
+	}
+	return closeErr
+}
+
+// ReadRecording reads a file written by RecorderTarget and returns its states
+// and the times they were recorded, oldest first.
+func ReadRecording(path string) ([]*ViewState, []time.Time, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open recording: %w", err)
+	}
+	defer file.Close()
+
+	var states []*ViewState
+	var times []time.Time
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 64<<20) // Large worlds make long lines
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record RecordJSON
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		states = append(states, JSONToViewState(record.World))
+		times = append(times, record.Timestamp)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("read recording: %w", err)
+	}
+	return states, times, nil
+}
@@ -0,0 +1,87 @@
+package nimsforestviewer
+
+import (
+	"context"
+	"sync"
+)
+
+// RecordingTarget keeps every state it receives in memory.
+// It is meant for tests that assert what a Viewer dispatched.
+type RecordingTarget struct {
+	// UpdateErr, if set, is returned from every Update. The state is still recorded.
+	UpdateErr error
+	// CloseErr, if set, is returned from Close.
+	CloseErr error
+
+	name   string
+	mu     sync.Mutex
+	states []*ViewState
+	closed bool
+}
+
+// NewRecordingTarget creates a recording target with the given name.
+func NewRecordingTarget(name string) *RecordingTarget {
+	return &RecordingTarget{name: name}
+}
+
+// Name implements Target.
+func (t *RecordingTarget) Name() string {
+	if t.name == "" {
+		return "Recording"
+	}
+	return t.name
+}
+
+// Update implements Target.
+func (t *RecordingTarget) Update(ctx context.Context, state *ViewState) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.states = append(t.states, state)
+	return t.UpdateErr
+}
+
+// Close implements Target.
+func (t *RecordingTarget) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	return t.CloseErr
+}
+
+// States returns every state received so far, oldest first.
+func (t *RecordingTarget) States() []*ViewState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*ViewState(nil), t.states...)
+}
+
+// LastState returns the most recent state, or nil if Update hasn't been called.
+func (t *RecordingTarget) LastState() *ViewState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.states) == 0 {
+		return nil
+	}
+	return t.states[len(t.states)-1]
+}
+
+// UpdateCount returns how many times Update has been called.
+func (t *RecordingTarget) UpdateCount() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.states)
+}
+
+// Closed reports whether Close has been called.
+func (t *RecordingTarget) Closed() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.closed
+}
+
+// Reset discards the recorded states.
+func (t *RecordingTarget) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.states = nil
+}
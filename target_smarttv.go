@@ -3,36 +3,184 @@ package nimsforestviewer
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"image"
+	"image/draw"
 	"image/jpeg"
+	"image/png"
+	"net"
+	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
-	sprites "github.com/nimsforest/nimsforestsprites"
 	smarttv "github.com/nimsforest/nimsforestsmarttv"
+	sprites "github.com/nimsforest/nimsforestsprites"
 )
 
 // SmartTVTarget displays static images on Smart TVs via DLNA.
 // Uses nimsforestsprites for passive rendering and nimsforestsmarttv for transport.
+// A single target can drive several TVs; each frame is rendered once and sent to all of them.
 type SmartTVTarget struct {
-	tv             *smarttv.TV
-	renderer       *smarttv.Renderer
-	sprites        *sprites.Renderer
-	useJFIF        bool // Convert to JFIF format for better TV compatibility
-	spriteOpts     sprites.Options
-	lastImageBytes []byte // Cache to avoid redundant updates
+	tvs             []*smarttv.TV
+	renderers       []*smarttv.Renderer // One per TV, as a renderer serializes its sends
+	format          ImageFormat
+	spriteOpts      sprites.Options
+	viewport        *Viewport // Optional region of the grid to display
+	aspectMode      AspectMode
+	scaler          ImageScaler // Resamples frames to the output resolution; nil uses ScaleBilinear
+	outWidth        int         // Output resolution; zero uses the sprite options' size
+	outHeight       int
+	optionalSprites bool   // Start without a sprite renderer if it can't be created
+	spritesErr      error  // Why there is no sprite renderer, in degraded mode
+	tempDir         string // Directory for JFIF conversion files; empty uses os.TempDir
+	ffmpegPath      string // Resolved at construction; empty uses the in-process JFIF encoder
+	magickPath      string // Optional; empty skips the imagemagick pass
+	jpegQuality     int    // 1-100; 0 keeps each encoder's default
+	sendTimeout     time.Duration
+	reconnect       bool         // Recreate the renderer and re-find TVs after a lost session
+	tvMu            sync.RWMutex // Guards tvs and renderers, which send replaces with sendMu held
+	keepAlive       time.Duration
+	sendMu          sync.Mutex // Serializes sends from Update and the keep-alive loop
+	lastData        []byte     // Most recently sent image, re-sent by the keep-alive loop
+	lastSent        time.Time
+	stopKeepAlive   chan struct{}
+	keepAliveDone   chan struct{}
+	pipeMu          sync.Mutex
+	pipe            *jpegPipe         // Persistent ffmpeg for JFIF conversion; started on first use
+	imageServer     *dlnaImageServer  // Serves formats smarttv.Renderer can't; nil for JPEG and JFIF
+	lastImageHash   [sha256.Size]byte // Last image every TV got; guarded by sendMu
+	hasLastImage    bool              // Guarded by sendMu
+	forceRedraw     bool              // Send every frame, even if unchanged
+	healthMu        sync.Mutex
+	sendErr         error // Result of the most recent send, reported by Health
+	closeOnce       sync.Once
+	spriteSource
+	themer
+	overlays
+	frameStats
+	loggable
+}
+
+// ImageFormat is the encoding SmartTVTarget sends to TVs.
+type ImageFormat string
+
+const (
+	// ImageFormatJPEG is a plain JPEG from the standard library encoder.
+	ImageFormatJPEG ImageFormat = "jpeg"
+	// ImageFormatJFIF is a JPEG with a JFIF header, which some TVs require. This is the default.
+	ImageFormatJFIF ImageFormat = "jfif"
+	// ImageFormatPNG is a lossless PNG, for renderers that reject JPEG.
+	ImageFormatPNG ImageFormat = "png"
+)
+
+// Viewport is a rectangular region of the land grid, measured in grid cells.
+type Viewport struct {
+	X, Y          int
+	Width, Height int
 }
 
 // TVOption configures a SmartTVTarget.
 type TVOption func(*SmartTVTarget)
 
 // WithJFIF enables JFIF conversion for better TV compatibility.
-// Requires ffmpeg and imagemagick to be installed.
+// Uses ffmpeg and imagemagick when installed, and an in-process encoder otherwise.
+// It is shorthand for WithImageFormat(ImageFormatJFIF) or WithImageFormat(ImageFormatJPEG).
 func WithJFIF(enable bool) TVOption {
 	return func(t *SmartTVTarget) {
-		t.useJFIF = enable
+		if enable {
+			t.format = ImageFormatJFIF
+		} else {
+			t.format = ImageFormatJPEG
+		}
+	}
+}
+
+// WithImageFormat sets the image encoding sent to the TVs. Defaults to ImageFormatJFIF.
+func WithImageFormat(format ImageFormat) TVOption {
+	return func(t *SmartTVTarget) {
+		t.format = format
+	}
+}
+
+// WithJPEGQuality sets the JPEG quality from 1 (smallest) to 100 (best) for the
+// JPEG and JFIF formats. Lower values suit slow links, higher values large screens.
+//
+// The standard library encoders use q directly. For ffmpeg it is mapped linearly
+// onto qscale, where 1 is best and 31 worst: q=100 gives -q:v 1, q=50 gives 17,
+// and q=1 gives 31. Without this option the defaults are 85 for JPEG, 90 for the
+// in-process JFIF encoder, and -q:v 2 for ffmpeg.
+func WithJPEGQuality(q int) TVOption {
+	return func(t *SmartTVTarget) {
+		t.jpegQuality = q
+	}
+}
+
+// WithBinaryPaths sets the locations of the ffmpeg and imagemagick executables
+// used for JFIF conversion. Empty values are looked up on PATH.
+func WithBinaryPaths(ffmpeg, magick string) TVOption {
+	return func(t *SmartTVTarget) {
+		t.ffmpegPath = ffmpeg
+		t.magickPath = magick
+	}
+}
+
+// WithTempDir sets the directory used for intermediate files during JFIF conversion.
+// Defaults to the system temp directory, which honors TMPDIR.
+func WithTempDir(dir string) TVOption {
+	return func(t *SmartTVTarget) {
+		t.tempDir = dir
+	}
+}
+
+// WithUpdateTimeout bounds how long sending a frame to the TVs may take, so a wedged
+// DLNA call fails the update instead of stalling the viewer. Defaults to 30s;
+// zero relies on the caller's context alone.
+func WithUpdateTimeout(d time.Duration) TVOption {
+	return func(t *SmartTVTarget) {
+		t.sendTimeout = d
+	}
+}
+
+// WithAutoReconnect makes a send that fails, typically because the TV dropped its
+// DLNA session after idling or a standby cycle, recreate that TV's DLNA renderer,
+// look it up again in case its control URL changed, and retry once. Sends that time
+// out or are canceled are not retried.
+func WithAutoReconnect(enable bool) TVOption {
+	return func(t *SmartTVTarget) {
+		t.reconnect = enable
+	}
+}
+
+// WithKeepAlive re-sends the last image whenever nothing has been sent for interval,
+// for TVs that return to their home screen when a photo isn't refreshed.
+// Zero, the default, disables it.
+func WithKeepAlive(interval time.Duration) TVOption {
+	return func(t *SmartTVTarget) {
+		t.keepAlive = interval
+	}
+}
+
+// WithForceRedraw sends every frame to the TVs, even one identical to the last,
+// instead of skipping unchanged frames. Use it for TVs that blank the screen when
+// their session drops and rejoins, so the frame is pushed again on the next update.
+func WithForceRedraw(enable bool) TVOption {
+	return func(t *SmartTVTarget) {
+		t.forceRedraw = enable
+	}
+}
+
+// WithSharedRenderer renders frames through r instead of a renderer of the target's own.
+// The renderer's options replace WithSpriteOptions.
+func WithSharedRenderer(r *SharedRenderer) TVOption {
+	return func(t *SmartTVTarget) {
+		t.sprites = r
 	}
 }
 
@@ -43,125 +191,677 @@ func WithSpriteOptions(opts sprites.Options) TVOption {
 	}
 }
 
+// WithViewport crops the rendered frame to a region of the land grid before encoding.
+// The region starts at grid cell (x, y) and spans w columns and h rows, so several
+// targets with adjacent viewports can tile one large picture across a video wall.
+func WithViewport(x, y, w, h int) TVOption {
+	return func(t *SmartTVTarget) {
+		t.viewport = &Viewport{X: x, Y: y, Width: w, Height: h}
+	}
+}
+
+// WithAspectMode sets how frames are fitted to the output resolution when their
+// aspect ratio differs, e.g. after WithViewport crops them. AspectFit, the default,
+// letterboxes with the theme background; AspectFill crops; AspectStretch distorts.
+// Without scaling here the TV stretches the frame itself, turning round tiles oval.
+func WithAspectMode(mode AspectMode) TVOption {
+	return func(t *SmartTVTarget) {
+		t.aspectMode = mode
+	}
+}
+
+// WithImageScaler sets how frames are resampled when the rendered size differs
+// from the output resolution. Defaults to ScaleBilinear; ScaleArea keeps labels
+// legible when shrinking a 1080p frame for a 720p panel.
+func WithImageScaler(s ImageScaler) TVOption {
+	return func(t *SmartTVTarget) {
+		t.scaler = s
+	}
+}
+
+// WithResolution sets the size of the images sent to the TV, such as its native
+// panel size. Defaults to the size of the rendered frame.
+func WithResolution(width, height int) TVOption {
+	return func(t *SmartTVTarget) {
+		t.outWidth, t.outHeight = width, height
+	}
+}
+
+// WithTimestampOverlay draws the current time in the bottom-right corner of each
+// frame, so a frozen clock on the wall shows the feed has stalled. Frames then
+// differ every second, so each update is sent even if the state hasn't changed.
+func WithTimestampOverlay(enable bool) TVOption {
+	return func(t *SmartTVTarget) {
+		t.timestamp = enable
+	}
+}
+
+// WithOverlayText draws a text banner, such as a title, in a corner of each frame.
+func WithOverlayText(text string, pos Corner) TVOption {
+	return func(t *SmartTVTarget) {
+		t.addText(text, pos)
+	}
+}
+
+// WithOverlayImage draws img, such as a logo, in a corner of each frame at its
+// own size, blended by its alpha channel.
+func WithOverlayImage(img image.Image, pos Corner) TVOption {
+	return func(t *SmartTVTarget) {
+		t.addImage(img, pos)
+	}
+}
+
+// WithRequireRenderer controls what happens when the sprite renderer can't be
+// created. By default NewSmartTVTarget fails; with false it returns a degraded
+// target that shows a placeholder frame and reports the problem through Health,
+// so the rest of the viewer can still start. Frames passed to UpdateImage, such
+// as those from WithFrameRenderer, are displayed normally.
+func WithRequireRenderer(require bool) TVOption {
+	return func(t *SmartTVTarget) {
+		t.optionalSprites = !require
+	}
+}
+
 // NewSmartTVTarget creates a target that displays images on a Smart TV.
 func NewSmartTVTarget(tv *smarttv.TV, opts ...TVOption) (*SmartTVTarget, error) {
+	return NewSmartTVGroupTarget([]*smarttv.TV{tv}, opts...)
+}
+
+// NewSmartTVGroupTarget creates a target that displays the same image on several Smart TVs,
+// such as the screens of a video wall.
+func NewSmartTVGroupTarget(tvs []*smarttv.TV, opts ...TVOption) (*SmartTVTarget, error) {
+	if len(tvs) == 0 {
+		return nil, fmt.Errorf("no TVs given")
+	}
+
 	target := &SmartTVTarget{
-		tv:      tv,
-		useJFIF: true, // Default to JFIF for better compatibility
-		spriteOpts: sprites.Options{
-			Width:     1920,
-			Height:    1080,
-			FrameRate: 30,
-			UseGPU:    false, // Use software rendering for headless
-		},
+		tvs:         append([]*smarttv.TV(nil), tvs...), // reconnectTV replaces entries
+		format:      ImageFormatJFIF,                    // Default to JFIF for better compatibility
+		spriteOpts:  defaultTVSpriteOptions(),
+		aspectMode:  AspectFit,
+		sendTimeout: 30 * time.Second,
 	}
 
 	for _, opt := range opts {
 		opt(target)
 	}
 
-	// Create smarttv renderer
-	renderer, err := smarttv.NewRenderer()
-	if err != nil {
-		return nil, fmt.Errorf("create smarttv renderer: %w", err)
+	if target.jpegQuality != 0 && (target.jpegQuality < 1 || target.jpegQuality > 100) {
+		return nil, fmt.Errorf("JPEG quality %d out of range 1-100", target.jpegQuality)
+	}
+
+	switch target.aspectMode {
+	case AspectFit, AspectFill, AspectStretch:
+	default:
+		return nil, fmt.Errorf("unsupported aspect mode %q", target.aspectMode)
+	}
+	if target.outWidth < 0 || target.outHeight < 0 {
+		return nil, fmt.Errorf("invalid resolution %dx%d", target.outWidth, target.outHeight)
+	}
+
+	switch target.format {
+	case ImageFormatJPEG, ImageFormatPNG:
+	case ImageFormatJFIF:
+		ffmpeg, err := findBinary("ffmpeg", target.ffmpegPath)
+		switch {
+		case err == nil:
+			target.ffmpegPath = ffmpeg
+			// imagemagick is optional; without it the ffmpeg output is sent as-is
+			target.magickPath, _ = findBinary("magick", target.magickPath)
+		case target.ffmpegPath != "":
+			// An explicitly configured path that doesn't exist is a mistake, not a fallback
+			return nil, fmt.Errorf("JFIF conversion: %w", err)
+		default:
+			// No external tools: use the in-process JFIF encoder
+			target.magickPath = ""
+		}
+	default:
+		return nil, fmt.Errorf("unsupported image format %q", target.format)
+	}
+
+	// Create a smarttv renderer per TV, so a slow TV doesn't hold up the others
+	for range tvs {
+		renderer, err := smarttv.NewRenderer()
+		if err != nil {
+			target.closeRenderers()
+			return nil, fmt.Errorf("create smarttv renderer: %w", err)
+		}
+		target.renderers = append(target.renderers, renderer)
 	}
-	target.renderer = renderer
 
-	// Create sprite renderer
-	spriteRenderer, err := sprites.New(target.spriteOpts)
+	// Create sprite renderer, unless a shared one was given
+	var err error
+	target.spriteOpts, err = target.initSprites(target.spriteOpts)
+	if err != nil && target.optionalSprites {
+		target.spritesErr = err // Degraded: show a placeholder instead
+	} else if err != nil {
+		target.closeRenderers()
+		return nil, err
+	}
+
+	if target.format == ImageFormatPNG {
+		server, err := newDLNAImageServer()
+		if err != nil {
+			target.closeSprites()
+			target.closeRenderers()
+			return nil, fmt.Errorf("create image server: %w", err)
+		}
+		target.imageServer = server
+	}
+
+	if target.keepAlive > 0 {
+		target.stopKeepAlive = make(chan struct{})
+		target.keepAliveDone = make(chan struct{})
+		go target.keepAliveLoop()
+	}
+
+	return target, nil
+}
+
+// ErrNoTVFound is returned by DiscoverAndAddTV when discovery finds no TV.
+var ErrNoTVFound = errors.New("no TV found on the network")
+
+// DiscoverAndAddTV discovers Smart TVs for up to timeout, creates a SmartTVTarget
+// for the first one found and adds it to v.
+func DiscoverAndAddTV(ctx context.Context, v *Viewer, timeout time.Duration, opts ...TVOption) (*SmartTVTarget, error) {
+	tvs, err := smarttv.Discover(ctx, timeout)
 	if err != nil {
-		renderer.Close()
-		return nil, fmt.Errorf("create sprite renderer: %w", err)
+		return nil, fmt.Errorf("discover TVs: %w", err)
+	}
+	if len(tvs) == 0 {
+		return nil, ErrNoTVFound
 	}
-	target.sprites = spriteRenderer
 
+	target, err := NewSmartTVTarget(&tvs[0], opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create target for TV %s: %w", tvs[0].Name, err)
+	}
+	if err := v.AddTarget(target); err != nil {
+		target.Close()
+		return nil, err
+	}
 	return target, nil
 }
 
 // Name implements Target.
 func (t *SmartTVTarget) Name() string {
-	if t.tv != nil {
-		return fmt.Sprintf("SmartTV(%s)", t.tv.Name)
+	tvs := t.tvList()
+	names := make([]string, 0, len(tvs))
+	for _, tv := range tvs {
+		if tv != nil {
+			names = append(names, tv.Name)
+		}
+	}
+	if len(names) == 0 {
+		return "SmartTV"
 	}
-	return "SmartTV"
+	return fmt.Sprintf("SmartTV(%s)", strings.Join(names, ", "))
 }
 
 // Update implements Target.
+// If the sprite renderer can encode frames itself and the frame needs no
+// processing here, its encoded bytes are sent as is, skipping a decode and
+// re-encode per frame; otherwise the frame goes through updateImage.
 func (t *SmartTVTarget) Update(ctx context.Context, state *ViewState) error {
 	// Convert ViewState to sprites.State
 	adapter := NewSpritesStateAdapter(state)
 
 	// Render frame
+	if t.sprites == nil {
+		return t.updateImage(ctx, placeholderFrame(t.spriteOpts, "Renderer unavailable"), nil)
+	}
+	if t.canPassthrough(state) {
+		data, ok, err := t.sprites.RenderEncoded(adapter, string(t.format), t.jpegQuality)
+		if err != nil {
+			return fmt.Errorf("render %s: %w", t.format, err)
+		}
+		if ok {
+			t.markUpdated()
+			return t.sendIfChanged(ctx, data)
+		}
+	}
 	frame := t.sprites.Render(adapter)
 	if frame == nil {
 		return fmt.Errorf("failed to render frame")
 	}
+	return t.updateImage(ctx, frame, failedCells(state, t.spriteOpts))
+}
+
+// UpdateImage implements ImageTarget.
+func (t *SmartTVTarget) UpdateImage(ctx context.Context, frame image.Image) error {
+	return t.updateImage(ctx, frame, nil)
+}
+
+// updateImage implements stateImageTarget.
+func (t *SmartTVTarget) updateImage(ctx context.Context, frame image.Image, failed []image.Rectangle) error {
+	frame = t.recolor(frame, failed)
+
+	// Crop to the configured grid region
+	if t.viewport != nil {
+		rect := gridRectToPixels(*t.viewport, t.spriteOpts)
+		if !rect.Overlaps(frame.Bounds()) {
+			return fmt.Errorf("viewport %+v is outside the rendered frame", *t.viewport)
+		}
+		frame = cropImage(frame, rect)
+	}
+
+	// Fit to the output resolution so the TV doesn't stretch the frame
+	width, height := t.outWidth, t.outHeight
+	if width == 0 || height == 0 {
+		width, height = t.spriteOpts.Width, t.spriteOpts.Height
+	}
+	frame = fitImage(frame, width, height, t.aspectMode, t.background(), t.scaler)
+	t.markUpdated()
+	frame = t.drawOverlays(frame, time.Now())
 
-	// Convert to JPEG
-	var jpegData []byte
+	// Encode in the configured format
+	var data []byte
 	var err error
-	if t.useJFIF {
-		jpegData, err = convertToJFIF(frame)
-	} else {
-		jpegData, err = encodeJPEG(frame)
+	switch {
+	case t.format == ImageFormatPNG:
+		data, err = encodePNG(frame)
+	case t.format == ImageFormatJFIF && t.ffmpegPath == "":
+		data, err = encodeJFIF(frame, t.jpegQuality)
+	case t.format == ImageFormatJFIF:
+		data, err = t.convertWithPipe(frame)
+	default:
+		data, err = encodeJPEG(frame, t.jpegQuality)
 	}
 	if err != nil {
-		return fmt.Errorf("convert to JPEG: %w", err)
+		return fmt.Errorf("encode %s: %w", t.format, err)
+	}
+	return t.sendIfChanged(ctx, data)
+}
+
+// canPassthrough reports whether the renderer's own encoding of state can be
+// sent as is: the renderer can encode, and the frame needs no recoloring,
+// cropping, scaling or overlays and the format no conversion.
+func (t *SmartTVTarget) canPassthrough(state *ViewState) bool {
+	if !t.sprites.canRenderEncoded() {
+		return false
+	}
+	if t.format != ImageFormatJPEG && t.format != ImageFormatPNG {
+		return false
 	}
+	if t.remap.Load() != nil || t.viewport != nil || len(t.items) > 0 || t.timestamp {
+		return false
+	}
+	if (t.outWidth != 0 || t.outHeight != 0) && (t.outWidth != t.spriteOpts.Width || t.outHeight != t.spriteOpts.Height) {
+		return false
+	}
+	// Failed processes are marked even without a theme
+	return len(failedCells(state, t.spriteOpts)) == 0
+}
 
-	// Skip if image hasn't changed
-	if bytes.Equal(jpegData, t.lastImageBytes) {
+// sendIfChanged sends an encoded image unless it matches the last one sent.
+// A frame is only remembered once it reached every TV, so a failed frame is
+// retried on the next update even if it hasn't changed.
+func (t *SmartTVTarget) sendIfChanged(ctx context.Context, data []byte) error {
+	hash := sha256.Sum256(data)
+	t.sendMu.Lock()
+	defer t.sendMu.Unlock()
+	unchanged := !t.forceRedraw && t.hasLastImage && hash == t.lastImageHash
+	t.recordFrame(len(data), unchanged)
+	if unchanged {
 		return nil
 	}
-	t.lastImageBytes = jpegData
+	if err := t.sendLocked(ctx, data); err != nil {
+		t.hasLastImage = false
+		return err
+	}
+	t.lastImageHash, t.hasLastImage = hash, true
+	return nil
+}
+
+// send displays an encoded image on every TV and records the result for Health.
+func (t *SmartTVTarget) send(ctx context.Context, data []byte) error {
+	t.sendMu.Lock()
+	defer t.sendMu.Unlock()
+	return t.sendLocked(ctx, data)
+}
 
-	// Display on TV
-	if err := t.renderer.DisplayImageJPEG(ctx, t.tv, jpegData); err != nil {
-		return fmt.Errorf("display on TV: %w", err)
+// sendLocked is send for callers that hold sendMu.
+func (t *SmartTVTarget) sendLocked(ctx context.Context, data []byte) error {
+	t.lastData, t.lastSent = data, time.Now()
+
+	if t.sendTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.sendTimeout)
+		defer cancel()
 	}
 
-	return nil
+	// Display on all TVs concurrently, each through its own renderer, so one
+	// slow or failing TV doesn't hold up the others
+	display := func(i int, tv *smarttv.TV) error {
+		return t.tvRenderer(i).DisplayImageJPEG(ctx, tv, data)
+	}
+	if t.imageServer != nil {
+		url := t.imageServer.store(data, "image/png", "png")
+		display = func(_ int, tv *smarttv.TV) error {
+			return dlnaDisplayImage(ctx, tv, url, "image/png")
+		}
+	}
+	// TVs found again at a new address are only stored once every send is done,
+	// so no goroutine sees another's TV change under it
+	moved := make([]*smarttv.TV, len(t.tvs))
+	err := t.forEachTV(func(i int, tv *smarttv.TV) error {
+		err := display(i, tv)
+		if err != nil && t.reconnect && isSessionLost(err) {
+			t.log().Info("TV session lost, reconnecting", "tv", tv.Name, "ip", tv.IP, "error", err)
+			fresh, rerr := t.reconnectTV(ctx, i, tv)
+			if rerr != nil {
+				t.log().Warn("TV reconnect failed", "tv", tv.Name, "ip", tv.IP, "error", rerr)
+			} else {
+				moved[i], tv = fresh, fresh
+				err = display(i, tv)
+			}
+		}
+		if err != nil {
+			t.log().Warn("TV send failed", "tv", tv.Name, "ip", tv.IP, "error", err)
+			return fmt.Errorf("display on TV %s: %w", tv.Name, err)
+		}
+		t.log().Debug("TV send succeeded", "tv", tv.Name, "ip", tv.IP, "bytes", len(data))
+		return nil
+	})
+
+	t.tvMu.Lock()
+	for i, tv := range moved {
+		if tv != nil {
+			t.tvs[i] = tv
+		}
+	}
+	t.tvMu.Unlock()
+
+	t.healthMu.Lock()
+	t.sendErr = err
+	t.healthMu.Unlock()
+	return err
 }
 
-// Close implements Target.
-func (t *SmartTVTarget) Close() error {
-	if t.sprites != nil {
-		t.sprites.Close()
+// Health implements HealthChecker by reporting whether the most recent image
+// reached every TV. It is healthy before the first send.
+// A target without a sprite renderer is unhealthy even if sends succeed.
+func (t *SmartTVTarget) Health() error {
+	t.healthMu.Lock()
+	defer t.healthMu.Unlock()
+	if t.sendErr == nil && t.spritesErr != nil {
+		return fmt.Errorf("degraded: %w", t.spritesErr)
 	}
-	if t.renderer != nil {
-		t.renderer.Close()
+	return t.sendErr
+}
+
+// Validate implements Validator by checking that every TV's DLNA control
+// endpoint accepts connections. Nothing is displayed.
+func (t *SmartTVTarget) Validate(ctx context.Context) error {
+	return t.forEachTV(func(_ int, tv *smarttv.TV) error {
+		u, err := url.Parse(tv.ControlURL)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("TV %s: invalid control URL %q", tv.Name, tv.ControlURL)
+		}
+		host := u.Host
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "80")
+		}
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", host)
+		if err != nil {
+			return fmt.Errorf("TV %s: %w", tv.Name, err)
+		}
+		return conn.Close()
+	})
+}
+
+// convertWithPipe converts a frame to JFIF through the persistent ffmpeg process,
+// starting or restarting it as needed. If the pipe fails, the frame is converted
+// with a one-off ffmpeg run instead and the pipe is restarted on the next frame.
+func (t *SmartTVTarget) convertWithPipe(frame image.Image) ([]byte, error) {
+	rgba := ensureRGBA(frame)
+	width, height := rgba.Bounds().Dx(), rgba.Bounds().Dy()
+
+	t.pipeMu.Lock()
+	defer t.pipeMu.Unlock()
+
+	if t.pipe != nil && (t.pipe.width != width || t.pipe.height != height) {
+		t.pipe.close()
+		t.pipe = nil
+	}
+	if t.pipe == nil {
+		pipe, err := startJPEGPipe(t.ffmpegPath, width, height, ffmpegQScale(t.jpegQuality))
+		if err == nil {
+			t.pipe = pipe
+		}
+	}
+
+	var data []byte
+	err := fmt.Errorf("ffmpeg pipe not running")
+	if t.pipe != nil {
+		data, err = t.pipe.encode(rgba)
+	}
+	if err != nil {
+		if t.pipe != nil {
+			t.pipe.close()
+			t.pipe = nil
+		}
+		return convertToJFIF(rgba, jfifConfig{
+			ffmpeg:  t.ffmpegPath,
+			magick:  t.magickPath,
+			tempDir: t.tempDir,
+			quality: t.jpegQuality,
+		})
+	}
+
+	if t.magickPath == "" {
+		return data, nil
+	}
+	if converted, err := runMagick(t.magickPath, data); err == nil {
+		return converted, nil
+	}
+	// Fallback to ffmpeg output if magick fails
+	return data, nil
+}
+
+// forEachTV runs fn for every TV and its index concurrently and joins the errors.
+func (t *SmartTVTarget) forEachTV(fn func(i int, tv *smarttv.TV) error) error {
+	tvs := t.tvList()
+	errs := make([]error, len(tvs))
+	var wg sync.WaitGroup
+	for i, tv := range tvs {
+		wg.Add(1)
+		go func(i int, tv *smarttv.TV) {
+			defer wg.Done()
+			errs[i] = fn(i, tv)
+		}(i, tv)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// keepAliveLoop re-sends the last image after every keepAlive interval without a send.
+func (t *SmartTVTarget) keepAliveLoop() {
+	defer close(t.keepAliveDone)
+
+	timer := time.NewTimer(t.keepAlive)
+	defer timer.Stop()
+	for {
+		select {
+		case <-t.stopKeepAlive:
+			return
+		case <-timer.C:
+		}
+
+		t.sendMu.Lock()
+		data, idle := t.lastData, time.Since(t.lastSent)
+		t.sendMu.Unlock()
+
+		if data == nil {
+			idle = 0 // Nothing to re-send yet
+		} else if idle >= t.keepAlive {
+			if err := t.send(context.Background(), data); err != nil {
+				t.log().Warn("TV keep-alive failed", "error", err)
+			}
+			idle = 0
+		}
+		timer.Reset(t.keepAlive - idle)
 	}
+}
+
+// Close implements Target.
+// Closing more than once is a no-op.
+func (t *SmartTVTarget) Close() error {
+	t.closeOnce.Do(func() {
+		if t.stopKeepAlive != nil {
+			close(t.stopKeepAlive)
+			<-t.keepAliveDone
+		}
+		t.closeSprites()
+		t.closeRenderers()
+		if t.imageServer != nil {
+			t.imageServer.close()
+		}
+		t.pipeMu.Lock()
+		if t.pipe != nil {
+			t.pipe.close()
+			t.pipe = nil
+		}
+		t.pipeMu.Unlock()
+	})
 	return nil
 }
 
-// Stop stops playback on the TV.
+// Stop stops playback on all TVs.
 func (t *SmartTVTarget) Stop(ctx context.Context) error {
-	return t.renderer.Stop(ctx, t.tv)
+	return t.forEachTV(func(i int, tv *smarttv.TV) error {
+		return t.tvRenderer(i).Stop(ctx, tv)
+	})
+}
+
+// tvList returns a copy of the TVs, which send may replace after a reconnect.
+func (t *SmartTVTarget) tvList() []*smarttv.TV {
+	t.tvMu.RLock()
+	defer t.tvMu.RUnlock()
+	return append([]*smarttv.TV(nil), t.tvs...)
+}
+
+// tvRenderer returns the DLNA renderer of the i-th TV, which reconnectTV may replace.
+func (t *SmartTVTarget) tvRenderer(i int) *smarttv.Renderer {
+	t.tvMu.RLock()
+	defer t.tvMu.RUnlock()
+	return t.renderers[i]
+}
+
+// closeRenderers closes every TV's DLNA renderer.
+func (t *SmartTVTarget) closeRenderers() {
+	t.tvMu.Lock()
+	defer t.tvMu.Unlock()
+	for _, renderer := range t.renderers {
+		renderer.Close()
+	}
+}
+
+// reconnectTV recreates the i-th TV's DLNA renderer, dropping its idea of an
+// active session. It then looks the TV up by IP and returns it as found, or tv
+// if it can't be found. tv itself is left alone.
+func (t *SmartTVTarget) reconnectTV(ctx context.Context, i int, tv *smarttv.TV) (*smarttv.TV, error) {
+	renderer, err := smarttv.NewRenderer()
+	if err != nil {
+		return nil, fmt.Errorf("create smarttv renderer: %w", err)
+	}
+	t.tvMu.Lock()
+	t.renderers[i].Close()
+	t.renderers[i] = renderer
+	t.tvMu.Unlock()
+
+	found, err := smarttv.Discover(ctx, tvRediscoverTimeout)
+	if err != nil {
+		return tv, nil // Keep the old address; the retry will tell whether it still works
+	}
+	for _, fresh := range found {
+		if fresh.IP == tv.IP {
+			return &fresh, nil
+		}
+	}
+	return tv, nil
+}
+
+// tvRediscoverTimeout bounds the SSDP search reconnectTV does to find a TV again.
+const tvRediscoverTimeout = 3 * time.Second
+
+// isSessionLost reports whether a send failed in a way that reconnecting may fix.
+// smarttv reports SOAP and UPnP rejections as untyped errors, which can't be told
+// apart from a dropped session, so every error counts except the send's context
+// ending: a timeout or Close doesn't mean the TV went away.
+func isSessionLost(err error) bool {
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// defaultTVSpriteOptions returns the sprite options used unless WithSpriteOptions overrides them.
+// The frame should match the panel so the TV doesn't rescale it, but smarttv.TV
+// does not report a resolution (DLNA renderers don't advertise one in their
+// device description), so every TV gets 1920x1080; use WithResolution for others.
+func defaultTVSpriteOptions() sprites.Options {
+	return sprites.Options{
+		Width:     1920,
+		Height:    1080,
+		FrameRate: 30,
+		UseGPU:    false, // Use software rendering for headless
+	}
+}
+
+// cropImage copies the part of img inside r into a new image with its origin at (0, 0).
+func cropImage(img image.Image, r image.Rectangle) *image.RGBA {
+	r = r.Intersect(img.Bounds())
+	dst := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, r.Min, draw.Src)
+	return dst
+}
+
+// jfifConfig holds the external programs and scratch directory used by convertToJFIF.
+type jfifConfig struct {
+	ffmpeg  string
+	magick  string // Optional; empty skips the imagemagick pass
+	tempDir string
+	quality int // 1-100, mapped onto ffmpeg's qscale; 0 uses -q:v 2
+}
+
+// ffmpegQScale maps a 1-100 JPEG quality onto ffmpeg's 1-31 qscale (lower is better).
+func ffmpegQScale(quality int) int {
+	if quality == 0 {
+		return 2
+	}
+	return 31 - (quality-1)*30/99
+}
+
+// findBinary resolves an external program, preferring an explicit path over a PATH lookup.
+func findBinary(name, path string) (string, error) {
+	if path == "" {
+		path = name
+	}
+	resolved, err := exec.LookPath(path)
+	if err != nil {
+		return "", fmt.Errorf("%s not found: %w", name, err)
+	}
+	return resolved, nil
 }
 
 // convertToJFIF converts an image to JFIF-compliant JPEG using ffmpeg + magick.
 // This produces JPEG files that are compatible with more TVs (especially JVC).
-func convertToJFIF(img image.Image) ([]byte, error) {
+// Intermediate files are written to a fresh directory under cfg.tempDir and removed afterwards.
+func convertToJFIF(img image.Image, cfg jfifConfig) ([]byte, error) {
 	bounds := img.Bounds()
 	width := bounds.Dx()
 	height := bounds.Dy()
+	rgba := ensureRGBA(img)
 
-	rgba, ok := img.(*image.RGBA)
-	if !ok {
-		rgba = image.NewRGBA(bounds)
-		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-			for x := bounds.Min.X; x < bounds.Max.X; x++ {
-				rgba.Set(x, y, img.At(x, y))
-			}
-		}
+	workDir, err := os.MkdirTemp(cfg.tempDir, "nimsforest_viewer_")
+	if err != nil {
+		return nil, fmt.Errorf("create temp dir: %w", err)
 	}
+	defer os.RemoveAll(workDir)
 
-	tmpFile := fmt.Sprintf("/tmp/viewer_%d.jpg", time.Now().UnixNano())
-	jfifFile := fmt.Sprintf("/tmp/viewer_%d_jfif.jpg", time.Now().UnixNano())
-	defer os.Remove(tmpFile)
-	defer os.Remove(jfifFile)
+	tmpFile := filepath.Join(workDir, "frame.jpg")
+	jfifFile := filepath.Join(workDir, "frame_jfif.jpg")
 
-	cmd := exec.Command("ffmpeg",
+	cmd := exec.Command(cfg.ffmpeg,
 		"-y", "-loglevel", "error",
 		"-f", "rawvideo",
 		"-pix_fmt", "rgba",
@@ -169,7 +869,7 @@ func convertToJFIF(img image.Image) ([]byte, error) {
 		"-i", "pipe:0",
 		"-vframes", "1",
 		"-pix_fmt", "yuvj420p",
-		"-q:v", "2",
+		"-q:v", strconv.Itoa(ffmpegQScale(cfg.quality)),
 		tmpFile,
 	)
 	cmd.Stdin = bytes.NewReader(rgba.Pix)
@@ -177,27 +877,39 @@ func convertToJFIF(img image.Image) ([]byte, error) {
 		return nil, fmt.Errorf("ffmpeg: %w", err)
 	}
 
-	cmd2 := exec.Command("magick", tmpFile, jfifFile)
+	if cfg.magick == "" {
+		return os.ReadFile(tmpFile)
+	}
+
+	cmd2 := exec.Command(cfg.magick, tmpFile, jfifFile)
 	if err := cmd2.Run(); err != nil {
-		// Fallback to ffmpeg output if magick not available
+		// Fallback to ffmpeg output if magick fails
 		return os.ReadFile(tmpFile)
 	}
 
 	return os.ReadFile(jfifFile)
 }
 
-// encodeJPEG encodes an image as standard JPEG (may not work on all TVs).
-func encodeJPEG(img image.Image) ([]byte, error) {
+// encodePNG encodes an image as PNG.
+func encodePNG(img image.Image) ([]byte, error) {
 	var buf bytes.Buffer
-	bounds := img.Bounds()
-	rgba := image.NewRGBA(bounds)
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			rgba.Set(x, y, img.At(x, y))
-		}
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
 	}
+	return buf.Bytes(), nil
+}
+
+// defaultJPEGQuality is the quality encodeJPEG uses when none is configured.
+const defaultJPEGQuality = 85
 
-	if err := jpeg.Encode(&buf, rgba, &jpeg.Options{Quality: 85}); err != nil {
+// encodeJPEG encodes an image as standard JPEG (may not work on all TVs).
+// A quality of 0 uses defaultJPEGQuality.
+func encodeJPEG(img image.Image, quality int) ([]byte, error) {
+	if quality == 0 {
+		quality = defaultJPEGQuality
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, ensureRGBA(img), &jpeg.Options{Quality: quality}); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
@@ -0,0 +1,200 @@
+package nimsforestviewer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ANSI escape sequences used by TerminalTarget.
+const (
+	ansiClear   = "\x1b[H\x1b[2J"
+	ansiReset   = "\x1b[0m"
+	ansiGreen   = "\x1b[32m"
+	ansiYellow  = "\x1b[33m"
+	ansiRed     = "\x1b[31m"
+	ansiMagenta = "\x1b[35m"
+	ansiBold    = "\x1b[1m"
+)
+
+// terminalCellWidth is the inner width of a land box in characters.
+const terminalCellWidth = 20
+
+// TerminalTarget renders the land grid as text for headless debugging.
+// Each land is drawn as a box showing its occupancy and process counts.
+type TerminalTarget struct {
+	w     io.Writer
+	color bool
+	mu    sync.Mutex
+}
+
+// TerminalOption configures a TerminalTarget.
+type TerminalOption func(*TerminalTarget)
+
+// WithTerminalWriter sets where the grid is drawn. Defaults to os.Stdout.
+func WithTerminalWriter(w io.Writer) TerminalOption {
+	return func(t *TerminalTarget) {
+		t.w = w
+	}
+}
+
+// WithColor enables ANSI colors: manalands in magenta, normal lands in green,
+// and occupancy bars shaded by load.
+func WithColor(enable bool) TerminalOption {
+	return func(t *TerminalTarget) {
+		t.color = enable
+	}
+}
+
+// NewTerminalTarget creates a target that draws the visualization as text.
+func NewTerminalTarget(opts ...TerminalOption) (*TerminalTarget, error) {
+	target := &TerminalTarget{
+		w: os.Stdout,
+	}
+
+	for _, opt := range opts {
+		opt(target)
+	}
+
+	return target, nil
+}
+
+// Name implements Target.
+func (t *TerminalTarget) Name() string {
+	return "Terminal"
+}
+
+// Update implements Target.
+// It clears the screen and redraws the grid.
+func (t *TerminalTarget) Update(ctx context.Context, state *ViewState) error {
+	var buf bytes.Buffer
+	buf.WriteString(ansiClear)
+	t.render(&buf, state)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, err := t.w.Write(buf.Bytes())
+	return err
+}
+
+// Close implements Target.
+func (t *TerminalTarget) Close() error {
+	return nil
+}
+
+func (t *TerminalTarget) render(buf *bytes.Buffer, state *ViewState) {
+	if state == nil || len(state.Lands) == 0 {
+		buf.WriteString("No lands reported yet\n")
+		return
+	}
+
+	// Place lands on the grid
+	cols, rows := 0, 0
+	grid := make(map[[2]int]*LandView, len(state.Lands))
+	for i, pos := range layoutGrid(state.Lands) {
+		x, y := pos.X, pos.Y
+		if x < 0 || y < 0 {
+			continue
+		}
+		grid[[2]int{x, y}] = &state.Lands[i]
+		cols = max(cols, x+1)
+		rows = max(rows, y+1)
+	}
+
+	for y := 0; y < rows; y++ {
+		lines := make([]strings.Builder, 5)
+		for x := 0; x < cols; x++ {
+			cell := t.renderLand(grid[[2]int{x, y}])
+			for i := range lines {
+				lines[i].WriteString(cell[i])
+				lines[i].WriteByte(' ')
+			}
+		}
+		for i := range lines {
+			buf.WriteString(strings.TrimRight(lines[i].String(), " "))
+			buf.WriteByte('\n')
+		}
+	}
+
+	s := state.Summary
+	fmt.Fprintf(buf, "\nLands: %d (%d mana)  Trees: %d  Treehouses: %d  Nims: %d  RAM: %s / %s\n",
+		s.TotalLands, s.TotalManalands, s.TotalTrees, s.TotalTreehouses, s.TotalNims,
+		Bytes(s.AllocatedRAM), Bytes(s.TotalRAM))
+}
+
+// renderLand returns the five lines of a land box, or blank lines for an empty cell.
+func (t *TerminalTarget) renderLand(land *LandView) [5]string {
+	var lines [5]string
+	if land == nil {
+		for i := range lines {
+			lines[i] = strings.Repeat(" ", terminalCellWidth+2)
+		}
+		return lines
+	}
+
+	border := ansiGreen
+	tag := ""
+	if land.IsManaland {
+		border = ansiMagenta
+		tag = "MANA"
+	}
+
+	name := land.Hostname
+	if name == "" {
+		name = land.ID
+	}
+	name = truncate(name, terminalCellWidth-len(tag)-1)
+	header := name + strings.Repeat(" ", terminalCellWidth-len([]rune(name))-len(tag)) + tag
+
+	const barWidth = terminalCellWidth - 7
+	occupancy := min(max(land.Occupancy, 0), 1)
+	filled := int(occupancy*barWidth + 0.5)
+	bar := t.paint(ansiStatusColor(landStatus(land)), strings.Repeat("#", filled)) + strings.Repeat(".", barWidth-filled)
+	occ := fmt.Sprintf("[%s] %3.0f%%", bar, occupancy*100)
+
+	counts := fmt.Sprintf("T:%d H:%d N:%d", len(land.Trees), len(land.Treehouses), len(land.Nims))
+	counts += strings.Repeat(" ", max(terminalCellWidth-len(counts), 0))
+
+	lines[0] = t.paint(border, "┌"+strings.Repeat("─", terminalCellWidth)+"┐")
+	lines[1] = t.paint(border, "│") + t.paint(ansiBold, header) + t.paint(border, "│")
+	lines[2] = t.paint(border, "│") + occ + t.paint(border, "│")
+	lines[3] = t.paint(border, "│") + counts + t.paint(border, "│")
+	lines[4] = t.paint(border, "└"+strings.Repeat("─", terminalCellWidth)+"┘")
+	return lines
+}
+
+// paint wraps s in an ANSI color when colors are enabled.
+func (t *TerminalTarget) paint(code, s string) string {
+	if !t.color || s == "" {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+// ansiStatusColor picks the ANSI color for a land status.
+func ansiStatusColor(status string) string {
+	switch status {
+	case LandStatusCritical:
+		return ansiRed
+	case LandStatusWarn:
+		return ansiYellow
+	default:
+		return ansiGreen
+	}
+}
+
+// truncate shortens s to at most n runes.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	if n <= 1 {
+		return string(r[:n])
+	}
+	return string(r[:n-1]) + "…"
+}
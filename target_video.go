@@ -4,16 +4,41 @@ import (
 	"context"
 	"fmt"
 	"image"
+	"image/draw"
 	"io"
 	"net"
 	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
-	sprites "github.com/nimsforest/nimsforestsprites"
 	smarttv "github.com/nimsforest/nimsforestsmarttv"
+	sprites "github.com/nimsforest/nimsforestsprites"
+)
+
+// VideoMode selects how VideoTarget produces video for the TV.
+type VideoMode int
+
+const (
+	// VideoModeHLS renders frames continuously and serves a live HLS playlist.
+	VideoModeHLS VideoMode = iota
+	// VideoModeMP4 pre-renders a fixed-duration MP4 when Start is called,
+	// for TVs that cannot play HLS.
+	VideoModeMP4
+)
+
+// defaultVideoCodec is the software H.264 encoder used unless WithVideoCodec is given.
+const defaultVideoCodec = "libx264"
+
+// HLS output settings for live streaming.
+const (
+	hlsPlaylistName = "stream.m3u8"
+	hlsSegmentTime  = 2 // Seconds per segment
+	hlsListSize     = 5 // Segments kept in the playlist
+	hlsStartTimeout = 15 * time.Second
 )
 
 // VideoTarget streams continuous video to Smart TVs.
@@ -21,23 +46,44 @@ import (
 type VideoTarget struct {
 	tv             *smarttv.TV
 	tvRenderer     *smarttv.Renderer
-	sprites        *sprites.Renderer
 	spriteOpts     sprites.Options
+	mode           VideoMode
 	fps            int
 	duration       time.Duration
-	httpServer     *http.Server
-	videoFile      string
+	httpServer     *http.Server // Guarded by mu
+	videoFile      string       // Guarded by mu
+	videoSeq       int          // Bumped by Refresh so the TV refetches the clip
+	outputPath     string       // Fixed path for the MP4; empty uses a temp file
+	keepVideo      bool         // Leave the MP4 on disk after Close
+	hlsDir         string
 	localIP        string
-	port           int
+	port           int // 0 picks a free port; set to the bound port once serving
 	mu             sync.Mutex
-	cancel         context.CancelFunc
+	cancel         context.CancelFunc // Stops the live encoder
+	liveDone       chan struct{}      // Closed when the live encoder exits
+	tempDir        string             // Directory for video files; empty uses os.TempDir
+	ffmpegPath     string
+	codec          string
+	bitrateKbps    int // 0 lets the encoder choose
 	state          *ViewState
 	stateProvider  StateProvider
+	sampleInterval time.Duration // Video time between state provider polls
+	closeOnce      sync.Once
+	spriteSource
+	themer
+	overlays
 }
 
 // VideoOption configures a VideoTarget.
 type VideoOption func(*VideoTarget)
 
+// WithVideoMode selects live HLS streaming (the default) or a pre-rendered MP4.
+func WithVideoMode(mode VideoMode) VideoOption {
+	return func(t *VideoTarget) {
+		t.mode = mode
+	}
+}
+
 // WithVideoFPS sets the video frame rate.
 func WithVideoFPS(fps int) VideoOption {
 	return func(t *VideoTarget) {
@@ -45,13 +91,109 @@ func WithVideoFPS(fps int) VideoOption {
 	}
 }
 
-// WithVideoDuration sets the video duration.
+// WithVideoDuration sets the video duration in VideoModeMP4.
 func WithVideoDuration(d time.Duration) VideoOption {
 	return func(t *VideoTarget) {
 		t.duration = d
 	}
 }
 
+// WithVideoTempDir sets the directory used for rendered video files and HLS segments.
+// Defaults to the system temp directory, which honors TMPDIR.
+func WithVideoTempDir(dir string) VideoOption {
+	return func(t *VideoTarget) {
+		t.tempDir = dir
+	}
+}
+
+// WithVideoFFmpegPath sets the location of the ffmpeg executable.
+// By default ffmpeg is looked up on PATH.
+func WithVideoFFmpegPath(path string) VideoOption {
+	return func(t *VideoTarget) {
+		t.ffmpegPath = path
+	}
+}
+
+// WithVideoCodec selects the ffmpeg video encoder, e.g. "h264_nvenc" or
+// "h264_videotoolbox" for hardware encoding. Defaults to "libx264".
+func WithVideoCodec(codec string) VideoOption {
+	return func(t *VideoTarget) {
+		t.codec = codec
+	}
+}
+
+// WithVideoBitrate sets the target video bitrate in kilobits per second.
+func WithVideoBitrate(kbps int) VideoOption {
+	return func(t *VideoTarget) {
+		t.bitrateKbps = kbps
+	}
+}
+
+// WithVideoSampleInterval sets how often, in video time, the state provider is
+// polled while rendering frames. Frames in between reuse the last sampled state.
+func WithVideoSampleInterval(d time.Duration) VideoOption {
+	return func(t *VideoTarget) {
+		t.sampleInterval = d
+	}
+}
+
+// WithKeepVideo leaves the pre-rendered MP4 on disk when the target is closed or
+// the clip is replaced by Refresh, e.g. to archive past cluster states.
+func WithKeepVideo(keep bool) VideoOption {
+	return func(t *VideoTarget) {
+		t.keepVideo = keep
+	}
+}
+
+// WithVideoOutputPath writes the pre-rendered MP4 to path instead of a temp file.
+// The file is kept after Close. Refresh encodes next to it and renames the new
+// clip over it once complete, so the file at path is never partly written.
+func WithVideoOutputPath(path string) VideoOption {
+	return func(t *VideoTarget) {
+		t.outputPath = path
+		t.keepVideo = true
+	}
+}
+
+// WithVideoPort sets the port the TV fetches the video from. Defaults to 8889;
+// 0 picks a free port, which lets several VideoTargets run side by side.
+func WithVideoPort(port int) VideoOption {
+	return func(t *VideoTarget) {
+		t.port = port
+	}
+}
+
+// WithVideoSharedRenderer renders frames through r instead of a renderer of the
+// target's own. The renderer's options replace WithVideoSpriteOptions.
+func WithVideoSharedRenderer(r *SharedRenderer) VideoOption {
+	return func(t *VideoTarget) {
+		t.sprites = r
+	}
+}
+
+// WithVideoTimestampOverlay draws the time on each frame, followed by the age of
+// the state once it is a second or more old. Live streams show the time each
+// frame was rendered; MP4 clips show when the clip was generated.
+func WithVideoTimestampOverlay(enable bool) VideoOption {
+	return func(t *VideoTarget) {
+		t.timestamp = enable
+	}
+}
+
+// WithVideoOverlayText draws a text banner in a corner of each frame, like WithOverlayText.
+func WithVideoOverlayText(text string, pos Corner) VideoOption {
+	return func(t *VideoTarget) {
+		t.addText(text, pos)
+	}
+}
+
+// WithVideoOverlayImage draws an image in a corner of each frame, like WithOverlayImage.
+func WithVideoOverlayImage(img image.Image, pos Corner) VideoOption {
+	return func(t *VideoTarget) {
+		t.addImage(img, pos)
+	}
+}
+
 // WithVideoSpriteOptions sets the sprite renderer options for video.
 func WithVideoSpriteOptions(opts sprites.Options) VideoOption {
 	return func(t *VideoTarget) {
@@ -62,22 +204,32 @@ func WithVideoSpriteOptions(opts sprites.Options) VideoOption {
 // NewVideoTarget creates a target that streams video to a Smart TV.
 func NewVideoTarget(tv *smarttv.TV, opts ...VideoOption) (*VideoTarget, error) {
 	target := &VideoTarget{
-		tv:  tv,
-		fps: 10,
-		duration: 60 * time.Second,
-		port: 8889,
-		spriteOpts: sprites.Options{
-			Width:     1920,
-			Height:    1080,
-			FrameRate: 30,
-			UseGPU:    false,
-		},
+		tv:             tv,
+		mode:           VideoModeHLS,
+		codec:          defaultVideoCodec,
+		fps:            10,
+		duration:       60 * time.Second,
+		port:           8889,
+		sampleInterval: time.Second,
+		spriteOpts:     defaultTVSpriteOptions(),
 	}
 
 	for _, opt := range opts {
 		opt(target)
 	}
 
+	ffmpeg, err := findBinary("ffmpeg", target.ffmpegPath)
+	if err != nil {
+		return nil, fmt.Errorf("video encoding requires ffmpeg (set WithVideoFFmpegPath): %w", err)
+	}
+	target.ffmpegPath = ffmpeg
+
+	if target.codec != defaultVideoCodec {
+		if err := checkEncoder(target.ffmpegPath, target.codec); err != nil {
+			return nil, err
+		}
+	}
+
 	// Create smarttv renderer
 	renderer, err := smarttv.NewRenderer()
 	if err != nil {
@@ -85,13 +237,12 @@ func NewVideoTarget(tv *smarttv.TV, opts ...VideoOption) (*VideoTarget, error) {
 	}
 	target.tvRenderer = renderer
 
-	// Create sprite renderer
-	spriteRenderer, err := sprites.New(target.spriteOpts)
+	// Create sprite renderer, unless a shared one was given
+	target.spriteOpts, err = target.initSprites(target.spriteOpts)
 	if err != nil {
 		renderer.Close()
-		return nil, fmt.Errorf("create sprite renderer: %w", err)
+		return nil, err
 	}
-	target.sprites = spriteRenderer
 
 	// Get local IP
 	target.localIP = getLocalIP()
@@ -108,6 +259,8 @@ func (t *VideoTarget) Name() string {
 }
 
 // SetStateProvider sets the state provider for continuous frame generation.
+// While rendering, the provider is polled once per sample interval; without a
+// provider the state from the last Update is rendered.
 func (t *VideoTarget) SetStateProvider(p StateProvider) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -115,23 +268,31 @@ func (t *VideoTarget) SetStateProvider(p StateProvider) {
 }
 
 // Update implements Target.
-// For VideoTarget, this is a no-op during streaming - use Start() to begin streaming.
+// It records the latest state; use Start() to begin streaming. In live HLS mode
+// the new state shows up on the TV within a few seconds.
 func (t *VideoTarget) Update(ctx context.Context, state *ViewState) error {
 	t.mu.Lock()
 	t.state = state
 	t.mu.Unlock()
+	t.markUpdated()
 	return nil
 }
 
 // Start begins video streaming to the TV.
-// This pre-renders a video file and streams it.
+// In VideoModeHLS this starts a live encoder that keeps running until ctx is
+// cancelled or the target is stopped. In VideoModeMP4 it pre-renders a video
+// file and streams it.
 func (t *VideoTarget) Start(ctx context.Context) error {
-	t.mu.Lock()
-	state := t.state
-	t.mu.Unlock()
+	if t.mode == VideoModeMP4 {
+		return t.startPrerendered(ctx)
+	}
+	return t.startLive(ctx)
+}
 
+func (t *VideoTarget) startPrerendered(ctx context.Context) error {
+	state := t.sampleState()
 	if state == nil {
-		return fmt.Errorf("no state set - call Update first")
+		return fmt.Errorf("no state set - call Update or SetStateProvider first")
 	}
 
 	// Generate video file
@@ -139,7 +300,9 @@ func (t *VideoTarget) Start(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("generate video: %w", err)
 	}
+	t.mu.Lock()
 	t.videoFile = videoFile
+	t.mu.Unlock()
 
 	// Start HTTP server
 	if err := t.startHTTPServer(ctx); err != nil {
@@ -155,25 +318,228 @@ func (t *VideoTarget) Start(ctx context.Context) error {
 	return nil
 }
 
-func (t *VideoTarget) generateVideo(ctx context.Context, state *ViewState) (string, error) {
+func (t *VideoTarget) startLive(ctx context.Context) error {
+	t.mu.Lock()
+	if t.cancel != nil {
+		t.mu.Unlock()
+		return fmt.Errorf("video stream already started")
+	}
+	hasState := t.state != nil || t.stateProvider != nil
+	t.mu.Unlock()
+
+	if !hasState {
+		return fmt.Errorf("no state set - call Update or SetStateProvider first")
+	}
+
+	dir, err := os.MkdirTemp(t.tempDir, "nimsforest_viewer_hls_")
+	if err != nil {
+		return fmt.Errorf("create HLS directory: %w", err)
+	}
+	t.hlsDir = dir
+
+	liveCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	if err := t.startLiveEncoder(liveCtx, dir, done); err != nil {
+		cancel()
+		os.RemoveAll(dir)
+		return fmt.Errorf("start live encoder: %w", err)
+	}
+
+	t.mu.Lock()
+	t.cancel = cancel
+	t.liveDone = done
+	t.mu.Unlock()
+
+	// The TV needs at least one segment before the playlist is playable
+	if err := waitForFile(ctx, filepath.Join(dir, hlsPlaylistName), hlsStartTimeout); err != nil {
+		t.stopLive()
+		return fmt.Errorf("wait for HLS playlist: %w", err)
+	}
+
+	if err := t.startHTTPServer(ctx); err != nil {
+		t.stopLive()
+		return fmt.Errorf("start HTTP server: %w", err)
+	}
+
+	streamURL := fmt.Sprintf("http://%s:%d/hls/%s", t.localIP, t.port, hlsPlaylistName)
+	if err := t.tvRenderer.StreamVideo(ctx, t.tv, streamURL, "nimsforest"); err != nil {
+		t.stopLive()
+		return fmt.Errorf("stream to TV: %w", err)
+	}
+
+	return nil
+}
+
+// startLiveEncoder launches ffmpeg writing an HLS playlist into dir and feeds it
+// frames until ctx is cancelled. done is closed once ffmpeg has exited.
+func (t *VideoTarget) startLiveEncoder(ctx context.Context, dir string, done chan struct{}) error {
+	args := []string{"-y", "-loglevel", "error",
+		"-f", "rawvideo",
+		"-pix_fmt", "rgba",
+		"-s", fmt.Sprintf("%dx%d", t.spriteOpts.Width, t.spriteOpts.Height),
+		"-r", fmt.Sprintf("%d", t.fps),
+		"-i", "pipe:0",
+	}
+	args = append(args, t.encoderArgs(true)...)
+	args = append(args,
+		"-g", fmt.Sprintf("%d", t.fps*hlsSegmentTime),
+		"-f", "hls",
+		"-hls_time", fmt.Sprintf("%d", hlsSegmentTime),
+		"-hls_list_size", fmt.Sprintf("%d", hlsListSize),
+		"-hls_flags", "delete_segments",
+		"-hls_segment_filename", filepath.Join(dir, "segment_%05d.ts"),
+		filepath.Join(dir, hlsPlaylistName),
+	)
+	ffmpeg := exec.CommandContext(ctx, t.ffmpegPath, args...)
+
+	ffmpegIn, err := ffmpeg.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("create pipe: %w", err)
+	}
+	ffmpeg.Stderr = io.Discard
+
+	if err := ffmpeg.Start(); err != nil {
+		return fmt.Errorf("start ffmpeg: %w", err)
+	}
+
+	go func() {
+		defer close(done)
+		t.streamFrames(ctx, ffmpegIn)
+		ffmpegIn.Close()
+		ffmpeg.Wait()
+	}()
+	return nil
+}
+
+// streamFrames renders frames in real time until ctx is cancelled or ffmpeg stops reading.
+func (t *VideoTarget) streamFrames(ctx context.Context, w io.Writer) {
+	ticker := time.NewTicker(time.Second / time.Duration(t.fps))
+	defer ticker.Stop()
+
+	sampleEvery := t.framesPerSample()
+	for i := 0; ; i++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Poll the provider periodically rather than on every frame
+		if i%sampleEvery == 0 {
+			t.sampleState()
+		}
+		t.mu.Lock()
+		state := t.state
+		t.mu.Unlock()
+
+		frame := t.sprites.Render(NewSpritesStateAdapter(state))
+		if frame == nil {
+			continue
+		}
+		if _, err := w.Write(ensureRGBA(t.drawOverlays(t.recolor(frame, failedCells(state, t.spriteOpts)), time.Now())).Pix); err != nil {
+			return
+		}
+	}
+}
+
+// framesPerSample converts the sample interval into a frame count.
+func (t *VideoTarget) framesPerSample() int {
+	n := int(t.sampleInterval.Seconds() * float64(t.fps))
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// sampleState refreshes the stored state from the state provider, if one is set.
+// The previous state is kept when the provider fails.
+func (t *VideoTarget) sampleState() *ViewState {
+	t.mu.Lock()
+	provider := t.stateProvider
+	state := t.state
+	t.mu.Unlock()
+
+	if provider == nil {
+		return state
+	}
+	fresh, err := provider.GetViewState()
+	if err != nil || fresh == nil {
+		return state
+	}
+
+	t.mu.Lock()
+	t.state = fresh
+	t.mu.Unlock()
+	t.markUpdated()
+	return fresh
+}
+
+// stopLive stops the live encoder, if running, and waits for it to exit.
+func (t *VideoTarget) stopLive() {
+	t.mu.Lock()
+	cancel, done := t.cancel, t.liveDone
+	t.cancel, t.liveDone = nil, nil
+	t.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// waitForFile polls until path exists, ctx is cancelled or timeout elapses.
+func waitForFile(ctx context.Context, path string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s not created within %s", filepath.Base(path), timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func (t *VideoTarget) generateVideo(ctx context.Context, state *ViewState) (videoFile string, err error) {
 	totalFrames := int(t.duration.Seconds()) * t.fps
-	videoFile := fmt.Sprintf("/tmp/nimsforest_viewer_%d.mp4", time.Now().UnixNano())
+
+	// Encode to a temp file; an output path is only replaced once encoding succeeds.
+	// The temp file shares its directory so the rename cannot cross filesystems.
+	dir, pattern := t.tempDir, "nimsforest_viewer_*.mp4"
+	if t.outputPath != "" {
+		dir, pattern = filepath.Dir(t.outputPath), "."+filepath.Base(t.outputPath)+".*.mp4"
+	}
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return "", fmt.Errorf("create video file: %w", err)
+	}
+	encodeFile := f.Name()
+	f.Close()
+
+	// Don't leave a partial file behind if encoding fails
+	defer func() {
+		if err != nil {
+			os.Remove(encodeFile)
+			videoFile = ""
+		}
+	}()
 
 	// Start ffmpeg encoder
-	ffmpeg := exec.CommandContext(ctx, "ffmpeg", "-y",
+	args := []string{"-y",
 		"-f", "rawvideo",
 		"-pix_fmt", "rgba",
 		"-s", fmt.Sprintf("%dx%d", t.spriteOpts.Width, t.spriteOpts.Height),
 		"-r", fmt.Sprintf("%d", t.fps),
 		"-i", "pipe:0",
-		"-c:v", "libx264",
-		"-preset", "ultrafast",
-		"-profile:v", "baseline",
-		"-level", "3.0",
-		"-pix_fmt", "yuv420p",
-		"-movflags", "+faststart",
-		videoFile,
-	)
+	}
+	args = append(args, t.encoderArgs(false)...)
+	args = append(args, "-movflags", "+faststart", encodeFile)
+	ffmpeg := exec.CommandContext(ctx, t.ffmpegPath, args...)
 
 	ffmpegIn, err := ffmpeg.StdinPipe()
 	if err != nil {
@@ -187,8 +553,11 @@ func (t *VideoTarget) generateVideo(ctx context.Context, state *ViewState) (stri
 
 	// Convert ViewState to sprites.State
 	adapter := NewSpritesStateAdapter(state)
+	failed := failedCells(state, t.spriteOpts)
+	sampleEvery := t.framesPerSample()
+	generated := time.Now()
 
-	// Render frames
+	// Render frames, resampling the provider so the clip follows state changes
 	for i := 0; i < totalFrames; i++ {
 		select {
 		case <-ctx.Done():
@@ -198,12 +567,19 @@ func (t *VideoTarget) generateVideo(ctx context.Context, state *ViewState) (stri
 		default:
 		}
 
+		if i > 0 && i%sampleEvery == 0 {
+			if fresh := t.sampleState(); fresh != nil {
+				adapter = NewSpritesStateAdapter(fresh)
+				failed = failedCells(fresh, t.spriteOpts)
+			}
+		}
+
 		frame := t.sprites.Render(adapter)
 		if frame == nil {
 			continue
 		}
 
-		rgba := ensureRGBA(frame)
+		rgba := ensureRGBA(t.drawOverlays(t.recolor(frame, failed), generated))
 		if _, err := ffmpegIn.Write(rgba.Pix); err != nil {
 			break
 		}
@@ -214,52 +590,176 @@ func (t *VideoTarget) generateVideo(ctx context.Context, state *ViewState) (stri
 		return "", fmt.Errorf("ffmpeg encode: %w", err)
 	}
 
-	return videoFile, nil
+	if t.outputPath == "" {
+		return encodeFile, nil
+	}
+	// Requests still serving the previous clip keep reading it through their open handle
+	if err := os.Rename(encodeFile, t.outputPath); err != nil {
+		return "", fmt.Errorf("replace video file: %w", err)
+	}
+	return t.outputPath, nil
 }
 
 func (t *VideoTarget) startHTTPServer(ctx context.Context) error {
 	mux := http.NewServeMux()
-	mux.HandleFunc("/stream.mp4", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "video/mp4")
-		http.ServeFile(w, r, t.videoFile)
-	})
+	if t.videoFile != "" {
+		mux.HandleFunc("/stream.mp4", func(w http.ResponseWriter, r *http.Request) {
+			t.mu.Lock()
+			videoFile := t.videoFile
+			t.mu.Unlock()
+			w.Header().Set("Content-Type", "video/mp4")
+			http.ServeFile(w, r, videoFile)
+		})
+	}
+	if t.hlsDir != "" {
+		files := http.StripPrefix("/hls/", http.FileServer(http.Dir(t.hlsDir)))
+		mux.HandleFunc("/hls/", func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case strings.HasSuffix(r.URL.Path, ".m3u8"):
+				w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+				w.Header().Set("Cache-Control", "no-cache")
+			case strings.HasSuffix(r.URL.Path, ".ts"):
+				w.Header().Set("Content-Type", "video/mp2t")
+			}
+			files.ServeHTTP(w, r)
+		})
+	}
 
-	t.httpServer = &http.Server{
-		Addr:    fmt.Sprintf(":%d", t.port),
-		Handler: mux,
+	// Replace the server from a previous Start, which serves stale files
+	t.mu.Lock()
+	old := t.httpServer
+	t.httpServer = nil
+	t.mu.Unlock()
+	if old != nil {
+		old.Close()
 	}
 
-	go func() {
-		t.httpServer.ListenAndServe()
-	}()
+	// Bind before returning so the TV is only given a URL that is being served
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", t.port))
+	if err != nil {
+		return fmt.Errorf("listen on port %d: %w", t.port, err)
+	}
+	t.port = listener.Addr().(*net.TCPAddr).Port
 
-	// Wait a moment for server to start
-	time.Sleep(100 * time.Millisecond)
+	server := &http.Server{Handler: mux}
+	t.mu.Lock()
+	t.httpServer = server
+	t.mu.Unlock()
+	go server.Serve(listener)
 	return nil
 }
 
-// Close implements Target.
-func (t *VideoTarget) Close() error {
-	if t.httpServer != nil {
-		t.httpServer.Shutdown(context.Background())
+// Refresh re-renders the clip from the latest state, swaps it in for the one being
+// served and tells the TV to reload it, keeping the HTTP server and DLNA session.
+// It only applies to VideoModeMP4 after Start; a live HLS stream already follows
+// Update, so Refresh is a no-op there.
+func (t *VideoTarget) Refresh(ctx context.Context) error {
+	if t.mode != VideoModeMP4 {
+		return nil
+	}
+	t.mu.Lock()
+	started := t.httpServer != nil
+	t.mu.Unlock()
+	if !started {
+		return fmt.Errorf("video target not started")
 	}
-	if t.sprites != nil {
-		t.sprites.Close()
+
+	state := t.sampleState()
+	if state == nil {
+		return fmt.Errorf("no state set - call Update or SetStateProvider first")
 	}
-	if t.tvRenderer != nil {
-		t.tvRenderer.Close()
+	videoFile, err := t.generateVideo(ctx, state)
+	if err != nil {
+		return fmt.Errorf("generate video: %w", err)
 	}
-	if t.videoFile != "" {
-		os.Remove(t.videoFile)
+
+	t.mu.Lock()
+	oldFile := t.videoFile
+	t.videoFile = videoFile
+	t.videoSeq++
+	seq := t.videoSeq
+	t.mu.Unlock()
+	if oldFile != "" && oldFile != videoFile && !t.keepVideo {
+		os.Remove(oldFile) // Requests already serving it keep their open handle
+	}
+
+	// A new URL makes the TV fetch the new clip instead of replaying a cached one
+	videoURL := fmt.Sprintf("http://%s:%d/stream.mp4?v=%d", t.localIP, t.port, seq)
+	if err := t.tvRenderer.StreamVideo(ctx, t.tv, videoURL, "nimsforest"); err != nil {
+		return fmt.Errorf("stream to TV: %w", err)
 	}
 	return nil
 }
 
-// Stop stops video playback on the TV.
+// Close implements Target.
+// Closing more than once is a no-op.
+func (t *VideoTarget) Close() error {
+	t.closeOnce.Do(func() {
+		t.stopLive()
+		t.mu.Lock()
+		server := t.httpServer
+		t.mu.Unlock()
+		if server != nil {
+			server.Shutdown(context.Background())
+		}
+		t.closeSprites()
+		if t.tvRenderer != nil {
+			t.tvRenderer.Close()
+		}
+		t.mu.Lock()
+		if t.videoFile != "" && !t.keepVideo {
+			os.Remove(t.videoFile)
+		}
+		t.mu.Unlock()
+		if t.hlsDir != "" {
+			os.RemoveAll(t.hlsDir)
+		}
+	})
+	return nil
+}
+
+// Stop stops video playback on the TV and the live encoder, if running.
 func (t *VideoTarget) Stop(ctx context.Context) error {
+	t.stopLive()
 	return t.tvRenderer.Stop(ctx, t.tv)
 }
 
+// encoderArgs returns the ffmpeg output arguments for the configured codec.
+// Live streams are tuned for low latency where the encoder supports it.
+func (t *VideoTarget) encoderArgs(live bool) []string {
+	args := []string{"-c:v", t.codec}
+	if t.codec == "libx264" {
+		args = append(args, "-preset", "ultrafast")
+		if live {
+			args = append(args, "-tune", "zerolatency")
+		}
+	}
+	if t.bitrateKbps > 0 {
+		args = append(args, "-b:v", fmt.Sprintf("%dk", t.bitrateKbps))
+	}
+	return append(args,
+		"-profile:v", "baseline",
+		"-level", "3.0",
+		"-pix_fmt", "yuv420p",
+	)
+}
+
+// checkEncoder verifies that the local ffmpeg build provides the given encoder.
+func checkEncoder(ffmpeg, codec string) error {
+	out, err := exec.Command(ffmpeg, "-hide_banner", "-encoders").Output()
+	if err != nil {
+		return fmt.Errorf("list ffmpeg encoders: %w", err)
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		// Lines look like: " V....D h264_nvenc  NVIDIA NVENC H.264 encoder"
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[1] == codec {
+			return nil
+		}
+	}
+	return fmt.Errorf("video encoder %q is not available in this ffmpeg build", codec)
+}
+
 // getLocalIP returns the local IP address.
 func getLocalIP() string {
 	conn, err := net.Dial("udp", "8.8.8.8:80")
@@ -270,18 +770,16 @@ func getLocalIP() string {
 	return conn.LocalAddr().(*net.UDPAddr).IP.String()
 }
 
-// ensureRGBA converts any image to RGBA.
+// ensureRGBA converts any image to RGBA whose Pix holds exactly the image's rows
+// back to back, so it can be piped to ffmpeg as a raw frame.
+// Tightly packed RGBA images, such as sprite renderer frames, are returned as-is.
 func ensureRGBA(img image.Image) *image.RGBA {
-	if rgba, ok := img.(*image.RGBA); ok {
+	bounds := img.Bounds()
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Stride == 4*bounds.Dx() {
 		return rgba
 	}
 
-	bounds := img.Bounds()
 	rgba := image.NewRGBA(bounds)
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			rgba.Set(x, y, img.At(x, y))
-		}
-	}
+	draw.Draw(rgba, bounds, img, bounds.Min, draw.Src)
 	return rgba
 }
@@ -3,45 +3,225 @@ package nimsforestviewer
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
+	"net"
 	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/nimsforest/nimsforestviewer/web"
 )
 
 // WebTarget serves the visualization via HTTP for web browsers.
-// It provides a JSON API at /api/viewmodel and can serve static assets.
+// It provides a JSON API at /api/viewmodel (with deltas at /api/viewmodel/diff
+// and single lands at /api/lands/{id}),
+// a server-sent event stream of summary changes at /api/summary/stream,
+// and serves the embedded interactive frontend at /, or static assets from a
+// directory when configured. WithHandlerFunc adds routes of its own.
 type WebTarget struct {
-	addr     string
-	server   *http.Server
-	state    *ViewState
-	mu       sync.RWMutex
-	webDir   string // Optional directory with static web assets
-	started  bool
+	addr         string
+	server       *http.Server
+	listener     net.Listener // Bound by start; its address is the resolved one
+	state        *ViewState
+	mu           sync.RWMutex
+	webDir       string   // Optional directory with static web assets
+	spa          bool     // Serve index.html for unknown non-asset paths
+	corsOrigins  []string // Origins allowed to call the API cross-origin; "*" allows any
+	started      bool
+	lastUpdate   time.Time        // When Update last stored state
+	staleAfter   time.Duration    // Age at which the frontend flags the data as stale
+	version      uint64           // Incremented on every Update
+	history      []versionedWorld // Ring buffer of recent worlds for diffs
+	historySize  int
+	samples      []summarySample // Ring buffer of recent summaries for /api/history
+	sampleSize   int
+	healthFn     func() map[string]error // Optional source of /health results
+	admin        TargetAdmin             // Enables /admin endpoints with adminToken
+	adminToken   string
+	adminFactory TargetFactory
+	summarySubs  map[chan SummaryJSON]struct{} // /api/summary/stream clients
+	closing      chan struct{}                 // Closed by Close to end streams
+	refresh      func()                        // Requests an update; set by the Viewer
+	routes       []customRoute                 // Extra routes from WithHandlerFunc
+	closeOnce    sync.Once
+	loggable
+}
+
+// customRoute is a route registered with WithHandlerFunc.
+type customRoute struct {
+	pattern string
+	handler http.HandlerFunc
+}
+
+// reservedPaths are the paths of the built-in routes, which custom routes may
+// not take over. Wildcards are compared by position, not name.
+var reservedPaths = []string{
+	"/",
+	"/health",
+	"/api/viewmodel",
+	"/api/viewmodel/diff",
+	"/api/history",
+	"/api/lands",
+	"/api/lands/{}",
+	"/api/summary/stream",
+	"/api/refresh",
+	"/admin/targets",
+	"/admin/targets/{}",
+}
+
+// wildcard matches a ServeMux path wildcard such as {id} or {rest...}.
+var wildcard = regexp.MustCompile(`\{[^}]*\}`)
+
+// summarySample is the summary captured at one update.
+type summarySample struct {
+	at      time.Time
+	summary SummaryView
+}
+
+// versionedWorld is a WorldJSON snapshot tagged with the version it was stored under.
+type versionedWorld struct {
+	version uint64
+	world   WorldJSON
 }
 
 // WebOption configures a WebTarget.
 type WebOption func(*WebTarget)
 
-// WithWebDir sets the directory containing static web assets.
+// WithWebDir serves static web assets from dir instead of the embedded frontend.
 func WithWebDir(dir string) WebOption {
 	return func(t *WebTarget) {
 		t.webDir = dir
 	}
 }
 
+// WithSPAFallback serves index.html for paths that match no file, so a single-page
+// app's client-side routes such as /land/abc work on reload. Paths with a file
+// extension, like a missing .js or .css asset, and /api/ paths still 404.
+func WithSPAFallback(enable bool) WebOption {
+	return func(t *WebTarget) {
+		t.spa = enable
+	}
+}
+
+// WithCORSOrigins allows browsers on the given origins, such as "https://dash.example.com",
+// to call the API. Pass "*" to allow any origin. By default no CORS headers are sent,
+// so only same-origin pages can read the API.
+func WithCORSOrigins(origins ...string) WebOption {
+	return func(t *WebTarget) {
+		t.corsOrigins = origins
+	}
+}
+
+// WithDiffHistory sets how many recent versions are kept for /api/viewmodel/diff.
+// Clients asking for a delta from an older version receive the full model.
+func WithDiffHistory(n int) WebOption {
+	return func(t *WebTarget) {
+		t.historySize = n
+	}
+}
+
+// WithHistory sets how many summary samples, one per update, are kept for /api/history.
+// Defaults to 300, i.e. five minutes at the default update interval. Zero disables history.
+func WithHistory(size int) WebOption {
+	return func(t *WebTarget) {
+		t.sampleSize = size
+	}
+}
+
+// WithStaleAfter sets how long after the last update the frontend flags the data
+// as stale. Defaults to 30s. It is sent as the summary's stale_after.
+func WithStaleAfter(d time.Duration) WebOption {
+	return func(t *WebTarget) {
+		t.staleAfter = d
+	}
+}
+
+// WithHealthCheck makes /health report fn's results, typically Viewer.Health.
+// The endpoint returns 503 with the failing targets if any error is non-nil.
+// Without it, /health always reports ok.
+func WithHealthCheck(fn func() map[string]error) WebOption {
+	return func(t *WebTarget) {
+		t.healthFn = fn
+	}
+}
+
+// WithHandlerFunc serves h at pattern alongside the built-in routes, e.g. a
+// config or about document at "GET /api/about". pattern uses http.ServeMux
+// syntax. NewWebTarget fails if it is invalid or takes over a built-in path
+// such as /api/viewmodel or /health.
+func WithHandlerFunc(pattern string, h http.HandlerFunc) WebOption {
+	return func(t *WebTarget) {
+		t.routes = append(t.routes, customRoute{pattern: pattern, handler: h})
+	}
+}
+
 // NewWebTarget creates a target that serves the visualization via HTTP.
 func NewWebTarget(addr string, opts ...WebOption) (*WebTarget, error) {
 	target := &WebTarget{
-		addr: addr,
+		addr:        addr,
+		historySize: 16,
+		sampleSize:  300,
+		staleAfter:  30 * time.Second,
+		summarySubs: make(map[chan SummaryJSON]struct{}),
+		closing:     make(chan struct{}),
 	}
 
 	for _, opt := range opts {
 		opt(target)
 	}
+	if err := target.checkRoutes(); err != nil {
+		return nil, err
+	}
 
 	return target, nil
 }
 
+// checkRoutes rejects custom routes on reserved paths, and ones http.ServeMux
+// would panic on when Handler registers them.
+func (t *WebTarget) checkRoutes() (err error) {
+	for _, route := range t.routes {
+		if route.handler == nil {
+			return fmt.Errorf("route %q: nil handler", route.pattern)
+		}
+		p := routePath(route.pattern)
+		for _, reserved := range reservedPaths {
+			if wildcard.ReplaceAllString(p, "{}") == reserved {
+				return fmt.Errorf("route %q overrides reserved path %s", route.pattern, p)
+			}
+		}
+	}
+	if len(t.routes) == 0 {
+		return nil
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid route: %v", r)
+		}
+	}()
+	t.handler("")
+	return nil
+}
+
+// routePath returns the path of a ServeMux pattern, without its method and host.
+func routePath(pattern string) string {
+	if i := strings.IndexAny(pattern, " \t"); i >= 0 {
+		pattern = strings.TrimLeft(pattern[i:], " \t")
+	}
+	if i := strings.IndexByte(pattern, '/'); i > 0 {
+		pattern = pattern[i:]
+	}
+	return pattern
+}
+
 // Name implements Target.
 func (t *WebTarget) Name() string {
 	return fmt.Sprintf("WebTarget(%s)", t.addr)
@@ -50,61 +230,346 @@ func (t *WebTarget) Name() string {
 // Update implements Target.
 func (t *WebTarget) Update(ctx context.Context, state *ViewState) error {
 	t.mu.Lock()
+	if t.state == nil || state == nil || t.state.Summary != state.Summary {
+		t.publishSummary(state)
+	}
 	t.state = state
+	t.lastUpdate = time.Now()
+	t.version++
+	world := ViewStateToJSON(state)
+	t.annotateSummary(&world.Summary, t.lastUpdate)
+	t.recordHistory(world)
+	t.recordSample(state)
 	wasStarted := t.started
 	t.mu.Unlock()
 
-	// Auto-start server on first update
+	// Auto-start server on first update; a failed bind is retried on the next one
 	if !wasStarted {
-		return t.start()
+		return t.Start()
 	}
 	return nil
 }
 
-// Handler returns the HTTP handler for embedding in existing servers.
+// Handler returns the HTTP handler for embedding in existing servers at their root.
+// To mount the viewer under a path, use HandlerWithPrefix.
 func (t *WebTarget) Handler() http.Handler {
+	return t.handler("")
+}
+
+// HandlerWithPrefix returns the HTTP handler for mounting the viewer under
+// prefix, such as "/viewer", in an existing server:
+//
+//	mux.Handle("/viewer/", webTarget.HandlerWithPrefix("/viewer"))
+//
+// Requests must carry the prefix. The frontend is served at prefix + "/", with a
+// <base> element added to index.html so its links resolve under the prefix.
+func (t *WebTarget) HandlerWithPrefix(prefix string) http.Handler {
+	prefix = "/" + strings.Trim(prefix, "/")
+	if prefix == "/" {
+		return t.Handler()
+	}
+	return http.StripPrefix(prefix, t.handler(prefix))
+}
+
+// handler builds the mux for Handler and HandlerWithPrefix. Its routes are
+// relative to prefix, which callers strip from requests.
+func (t *WebTarget) handler(prefix string) http.Handler {
 	mux := http.NewServeMux()
 
-	// API endpoint
-	mux.HandleFunc("/api/viewmodel", t.handleViewmodel)
+	// API endpoints
+	mux.Handle("/api/viewmodel", t.cors(t.handleViewmodel))
+	mux.Handle("/api/viewmodel/diff", t.cors(t.handleViewmodelDiff))
+	mux.Handle("/api/history", t.cors(t.handleHistory))
+	mux.Handle("/api/lands", t.cors(t.handleLands))
+	mux.Handle("/api/lands/{id}", t.cors(t.handleLand))
+	mux.Handle("/api/summary/stream", t.cors(t.handleSummaryStream))
+
+	mux.HandleFunc("POST /api/refresh", t.handleRefresh)
 
 	// Health check
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("ok"))
-	})
+	mux.HandleFunc("/health", t.handleHealth)
+
+	t.registerAdmin(mux)
+
+	for _, route := range t.routes {
+		mux.HandleFunc(route.pattern, route.handler)
+	}
 
 	// Static files
+	var static http.Handler
+	var assets fs.FS
 	if t.webDir != "" {
-		mux.Handle("/", http.FileServer(http.Dir(t.webDir)))
+		assets = os.DirFS(t.webDir)
+		static = staticCacheHeaders(http.FileServer(http.Dir(t.webDir)), nil)
 	} else {
-		// Serve a simple status page if no web assets
-		mux.HandleFunc("/", t.handleIndex)
+		assets = web.FS()
+		static = staticCacheHeaders(http.FileServer(http.FS(assets)), web.ETags())
+	}
+	if prefix != "" {
+		static = serveIndexWithBase(static, assets, prefix+"/")
+	}
+	if t.spa {
+		static = spaFallback(static, assets)
 	}
+	mux.Handle("/", static)
 
 	return mux
 }
 
+// SetRefresh implements RefreshingTarget. POST /api/refresh calls refresh.
+func (t *WebTarget) SetRefresh(refresh func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.refresh = refresh
+}
+
+// handleRefresh asks the viewer for an update and returns 202 without waiting for it.
+// Requests are debounced by the viewer, so a flood of them causes few updates.
+func (t *WebTarget) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	t.mu.RLock()
+	refresh := t.refresh
+	t.mu.RUnlock()
+	if refresh == nil {
+		http.Error(w, "refresh is not available", http.StatusNotImplemented)
+		return
+	}
+	refresh()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handleHealth writes "ok", or with a health check configured, a JSON object
+// mapping each target to "ok" or its error.
+func (t *WebTarget) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if t.healthFn == nil {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+		return
+	}
+
+	status := http.StatusOK
+	targets := make(map[string]string)
+	for name, err := range t.healthFn() {
+		targets[name] = "ok"
+		if err != nil {
+			targets[name] = err.Error()
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	t.writeJSON(w, r, status, struct {
+		Healthy bool              `json:"healthy"`
+		Targets map[string]string `json:"targets"`
+	}{status == http.StatusOK, targets})
+}
+
+// writeJSON marshals v and writes it with status. Marshaling completes before
+// anything is sent, so a failure becomes a logged 500 rather than a truncated
+// 200. A failed write means the client went away and is only logged.
+func (t *WebTarget) writeJSON(w http.ResponseWriter, r *http.Request, status int, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		t.log().Error("encode JSON response", "path", r.URL.Path, "error", err)
+		w.Header().Del("ETag")
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	body = append(body, '\n')
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(status)
+	if _, err := w.Write(body); err != nil {
+		t.log().Debug("write JSON response", "path", r.URL.Path, "error", err)
+	}
+}
+
+// handleViewmodel serves the full WorldJSON, or with ?fields=summary, ?fields=lands
+// or both comma-separated, only those parts alongside version and generated_at.
 func (t *WebTarget) handleViewmodel(w http.ResponseWriter, r *http.Request) {
+	fields, err := parseFields(r.URL.Query().Get("fields"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	t.mu.RLock()
 	state := t.state
+	version := t.version
+	lastUpdate := t.lastUpdate
 	t.mu.RUnlock()
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("ETag", strconv.Quote(strconv.FormatUint(version, 10)))
+
+	if fields == nil {
+		worldJSON := ViewStateToJSON(state)
+		t.annotateSummary(&worldJSON.Summary, lastUpdate)
+		t.writeJSON(w, r, http.StatusOK, worldJSON)
+		return
+	}
+
+	// Only build the lands when asked for; they dominate the cost
+	var worldJSON WorldJSON
+	if fields["lands"] || fields["edges"] {
+		worldJSON = ViewStateToJSON(state)
+	} else {
+		worldJSON = WorldJSON{Version: SchemaVersion, GeneratedAt: time.Now()}
+		if state != nil {
+			worldJSON.Summary = summaryToJSON(state.Summary)
+		}
+	}
+	t.annotateSummary(&worldJSON.Summary, lastUpdate)
 
-	if state == nil {
-		json.NewEncoder(w).Encode(WorldJSON{})
+	payload := map[string]any{
+		"version":      worldJSON.Version,
+		"generated_at": worldJSON.GeneratedAt,
+	}
+	if fields["lands"] {
+		payload["lands"] = worldJSON.Lands
+	}
+	if fields["edges"] {
+		payload["edges"] = worldJSON.Edges
+	}
+	if fields["summary"] {
+		payload["summary"] = worldJSON.Summary
+	}
+	t.writeJSON(w, r, http.StatusOK, payload)
+}
+
+// annotateSummary adds the update time and staleness threshold to a summary.
+func (t *WebTarget) annotateSummary(s *SummaryJSON, lastUpdate time.Time) {
+	s.LastUpdate = lastUpdate
+	s.StaleAfter = t.staleAfter.Seconds()
+}
+
+// parseFields parses a comma-separated list of top-level WorldJSON fields.
+// An empty list returns nil, meaning every field.
+func parseFields(s string) (map[string]bool, error) {
+	if s == "" {
+		return nil, nil
+	}
+	fields := make(map[string]bool)
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		switch field {
+		case "lands", "edges", "summary":
+			fields[field] = true
+		default:
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+	}
+	return fields, nil
+}
+
+func (t *WebTarget) handleViewmodelDiff(w http.ResponseWriter, r *http.Request) {
+	since, sinceErr := parseVersion(r.URL.Query().Get("since"))
+
+	t.mu.RLock()
+	version := t.version
+	var current, base WorldJSON
+	var haveCurrent, haveBase bool
+	for _, entry := range t.history {
+		if entry.version == version {
+			current, haveCurrent = entry.world, true
+		}
+		if entry.version == since {
+			base, haveBase = entry.world, true
+		}
+	}
+	t.mu.RUnlock()
+
+	w.Header().Set("ETag", strconv.Quote(strconv.FormatUint(version, 10)))
+
+	if !haveCurrent {
+		t.writeJSON(w, r, http.StatusOK, WorldDiffJSON{Full: true, Lands: []LandJSON{}})
 		return
 	}
 
-	worldJSON := ViewStateToJSON(state)
-	json.NewEncoder(w).Encode(worldJSON)
+	diff := WorldDiffJSON{
+		Version: version,
+		Since:   since,
+		Summary: current.Summary,
+	}
+	if sinceErr != nil || !haveBase {
+		// Unknown or expired version: send everything so the client can resync
+		diff.Since = 0
+		diff.Full = true
+		diff.Lands = current.Lands
+	} else {
+		diff.Lands, diff.Removed = DiffWorldJSON(base, current)
+	}
+	t.writeJSON(w, r, http.StatusOK, diff)
+}
+
+// handleHistory returns the recorded summaries, limited to the last ?window=<duration> if given.
+func (t *WebTarget) handleHistory(w http.ResponseWriter, r *http.Request) {
+	var window time.Duration
+	if s := r.URL.Query().Get("window"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			http.Error(w, "invalid window: expected a positive duration such as 5m", http.StatusBadRequest)
+			return
+		}
+		window = d
+	}
+
+	history := HistoryJSON{Samples: []HistorySampleJSON{}}
+	cutoff := time.Now().Add(-window)
+
+	t.mu.RLock()
+	for _, sample := range t.samples {
+		if window > 0 && sample.at.Before(cutoff) {
+			continue
+		}
+		history.Samples = append(history.Samples, HistorySampleJSON{
+			Timestamp: sample.at,
+			Summary:   summaryToJSON(sample.summary),
+		})
+	}
+	t.mu.RUnlock()
+
+	t.writeJSON(w, r, http.StatusOK, history)
 }
 
-func (t *WebTarget) handleIndex(w http.ResponseWriter, r *http.Request) {
-	if r.URL.Path != "/" {
-		http.NotFound(w, r)
+// Page sizes for /api/lands.
+const (
+	defaultLandsLimit = 100
+	maxLandsLimit     = 1000
+)
+
+// landSorts orders lands for /api/lands by the sort query parameter.
+var landSorts = map[string]func(a, b LandJSON) bool{
+	"grid": func(a, b LandJSON) bool {
+		if a.GridY != b.GridY {
+			return a.GridY < b.GridY
+		}
+		return a.GridX < b.GridX
+	},
+	"hostname":  func(a, b LandJSON) bool { return a.Hostname < b.Hostname },
+	"occupancy": func(a, b LandJSON) bool { return a.Occupancy > b.Occupancy },
+	"ram":       func(a, b LandJSON) bool { return a.RAMTotal > b.RAMTotal },
+}
+
+// handleLands serves a page of lands selected by offset and limit, ordered by
+// sort: grid (the default), hostname, or occupancy or ram, largest first.
+func (t *WebTarget) handleLands(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	offset, err := parseQueryInt(query.Get("offset"), 0)
+	if err != nil || offset < 0 {
+		http.Error(w, "invalid offset: expected a non-negative integer", http.StatusBadRequest)
+		return
+	}
+	limit, err := parseQueryInt(query.Get("limit"), defaultLandsLimit)
+	if err != nil || limit < 1 || limit > maxLandsLimit {
+		http.Error(w, fmt.Sprintf("invalid limit: expected 1-%d", maxLandsLimit), http.StatusBadRequest)
+		return
+	}
+	sortBy := query.Get("sort")
+	if sortBy == "" {
+		sortBy = "grid"
+	}
+	less, ok := landSorts[sortBy]
+	if !ok {
+		http.Error(w, "invalid sort: expected grid, hostname, occupancy or ram", http.StatusBadRequest)
 		return
 	}
 
@@ -112,39 +577,141 @@ func (t *WebTarget) handleIndex(w http.ResponseWriter, r *http.Request) {
 	state := t.state
 	t.mu.RUnlock()
 
-	w.Header().Set("Content-Type", "text/html")
+	lands := ViewStateToJSON(state).Lands
+	sort.SliceStable(lands, func(i, j int) bool { return less(lands[i], lands[j]) })
+
+	page := LandsPageJSON{Total: len(lands), Offset: offset, Limit: limit, Lands: []LandJSON{}}
+	if offset < len(lands) {
+		page.Lands = lands[offset:min(offset+limit, len(lands))]
+	}
+
+	t.writeJSON(w, r, http.StatusOK, page)
+}
+
+// handleLand serves the land whose ID is the last path element, with all its
+// processes, or 404 if the current state has no such land.
+func (t *WebTarget) handleLand(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	t.mu.RLock()
+	state := t.state
+	version := t.version
+	t.mu.RUnlock()
 
-	landCount := 0
 	if state != nil {
-		landCount = len(state.Lands)
-	}
-
-	html := fmt.Sprintf(`<!DOCTYPE html>
-<html>
-<head>
-    <title>nimsforestviewer</title>
-    <style>
-        body { font-family: system-ui; background: #1a1a2e; color: #eee; padding: 2rem; }
-        h1 { color: #4ade80; }
-        .info { background: #16213e; padding: 1rem; border-radius: 8px; margin: 1rem 0; }
-        a { color: #60a5fa; }
-    </style>
-</head>
-<body>
-    <h1>nimsforestviewer</h1>
-    <div class="info">
-        <p><strong>Status:</strong> Running</p>
-        <p><strong>Lands:</strong> %d</p>
-        <p><strong>API:</strong> <a href="/api/viewmodel">/api/viewmodel</a></p>
-    </div>
-    <p>For the full interactive visualization, configure WebTarget with a web assets directory.</p>
-</body>
-</html>`, landCount)
-
-	w.Write([]byte(html))
-}
-
-func (t *WebTarget) start() error {
+		positions := layoutGrid(state.Lands)
+		for i, land := range state.Lands {
+			if land.ID == id {
+				w.Header().Set("ETag", strconv.Quote(strconv.FormatUint(version, 10)))
+				t.writeJSON(w, r, http.StatusOK, landToJSON(land, positions[i]))
+				return
+			}
+		}
+	}
+	http.Error(w, fmt.Sprintf("land %q not found", id), http.StatusNotFound)
+}
+
+// parseQueryInt parses an integer query parameter, returning def when it is empty.
+func parseQueryInt(s string, def int) (int, error) {
+	if s == "" {
+		return def, nil
+	}
+	return strconv.Atoi(s)
+}
+
+// recordSample appends the state's summary to the history ring buffer.
+// Callers must hold t.mu.
+func (t *WebTarget) recordSample(state *ViewState) {
+	if t.sampleSize <= 0 || state == nil {
+		return
+	}
+	if len(t.samples) >= t.sampleSize {
+		t.samples = append(t.samples[:0], t.samples[len(t.samples)-t.sampleSize+1:]...)
+	}
+	t.samples = append(t.samples, summarySample{at: time.Now(), summary: state.Summary})
+}
+
+// cors adds CORS headers for allowed origins and answers preflight requests.
+func (t *WebTarget) cors(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		allowed := t.allowOrigin(origin)
+		if allowed != "" {
+			w.Header().Set("Access-Control-Allow-Origin", allowed)
+			w.Header().Set("Access-Control-Expose-Headers", "ETag")
+		}
+		if allowed != "*" && len(t.corsOrigins) > 0 {
+			// The response depends on the request's origin
+			w.Header().Add("Vary", "Origin")
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			if allowed != "" {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, If-None-Match")
+				w.Header().Set("Access-Control-Max-Age", "600")
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for origin, or "" if it isn't allowed.
+func (t *WebTarget) allowOrigin(origin string) string {
+	if origin == "" {
+		return ""
+	}
+	for _, o := range t.corsOrigins {
+		if o == "*" {
+			return "*"
+		}
+		if strings.EqualFold(o, origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// spaFallback serves the root index for requests that match no file in assets
+// and don't look like asset or API requests.
+func spaFallback(static http.Handler, assets fs.FS) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+		if name != "" && path.Ext(name) == "" && !strings.HasPrefix(name, "api/") {
+			if _, err := fs.Stat(assets, name); errors.Is(err, fs.ErrNotExist) {
+				r = r.Clone(r.Context())
+				r.URL.Path, r.URL.RawPath = "/", ""
+			}
+		}
+		static.ServeHTTP(w, r)
+	})
+}
+
+// recordHistory appends world to the diff ring buffer under the current version.
+// Callers must hold t.mu.
+func (t *WebTarget) recordHistory(world WorldJSON) {
+	size := t.historySize
+	if size < 1 {
+		size = 1
+	}
+	if len(t.history) >= size {
+		t.history = append(t.history[:0], t.history[len(t.history)-size+1:]...)
+	}
+	t.history = append(t.history, versionedWorld{version: t.version, world: world})
+}
+
+// parseVersion parses a version given either as a sequence number or as an ETag.
+func parseVersion(s string) (uint64, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "W/")
+	return strconv.ParseUint(strings.Trim(s, `"`), 10, 64)
+}
+
+// Start binds the listener and begins serving in the background.
+// Update calls it automatically; calling it first surfaces bind errors, such as
+// the port being in use, before any state is available. Starting twice is a no-op.
+func (t *WebTarget) Start() error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
@@ -152,31 +719,92 @@ func (t *WebTarget) start() error {
 		return nil
 	}
 
+	// Bind here rather than in the goroutine so a port in use is reported to the caller
+	addr := t.addr
+	if addr == "" {
+		addr = ":http"
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", t.addr, err)
+	}
+	t.listener = listener
+
+	logger := t.log()
 	t.server = &http.Server{
-		Addr:    t.addr,
-		Handler: t.Handler(),
+		Handler:  t.Handler(),
+		ErrorLog: httpErrorLog(logger, t.Name()),
 	}
 
 	go func() {
-		t.server.ListenAndServe()
+		err := t.server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("web server stopped", "addr", listener.Addr().String(), "error", err)
+		}
 	}()
+	logger.Info("web server started", "addr", listener.Addr().String())
 
 	t.started = true
 	return nil
 }
 
+// Validate implements Validator by checking that the listen address can be
+// bound. A started target is valid, since it already holds the address.
+func (t *WebTarget) Validate(ctx context.Context) error {
+	t.mu.RLock()
+	started := t.started
+	t.mu.RUnlock()
+	if started {
+		return nil
+	}
+
+	addr := t.addr
+	if addr == "" {
+		addr = ":http"
+	}
+	var lc net.ListenConfig
+	listener, err := lc.Listen(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", t.addr, err)
+	}
+	return listener.Close()
+}
+
 // Close implements Target.
 func (t *WebTarget) Close() error {
-	t.mu.Lock()
-	defer t.mu.Unlock()
+	t.closeOnce.Do(func() { close(t.closing) })
+
+	// Shutdown waits for handlers, which may need t.mu to finish, so don't hold it
+	t.mu.RLock()
+	server := t.server
+	t.mu.RUnlock()
 
-	if t.server != nil {
-		return t.server.Shutdown(context.Background())
+	if server != nil {
+		return server.Shutdown(context.Background())
 	}
 	return nil
 }
 
+// ResolvedAddr returns the address the server is listening on, with the actual
+// port when addr was ":0". Before the server starts it returns the configured addr.
+func (t *WebTarget) ResolvedAddr() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.listener == nil {
+		return t.addr
+	}
+	return t.listener.Addr().String()
+}
+
 // URL returns the URL where the web target is serving.
+// Unspecified hosts such as ":8080" or "0.0.0.0:8080" are shown as localhost.
 func (t *WebTarget) URL() string {
-	return "http://localhost" + t.addr
+	host, port, err := net.SplitHostPort(t.ResolvedAddr())
+	if err != nil {
+		return "http://localhost" + t.addr
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
 }
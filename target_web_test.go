@@ -0,0 +1,83 @@
+package nimsforestviewer
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebTargetCloseWithOpenSummaryStream(t *testing.T) {
+	target, err := NewWebTarget("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := target.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := target.Update(context.Background(), &ViewState{Summary: SummaryView{TotalLands: 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Get(target.URL() + "/api/summary/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// Wait for the first event so the handler is known to be streaming
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(line, "event: summary") {
+		t.Fatalf("first line = %q, want a summary event", line)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- target.Close() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not return with a summary stream open")
+	}
+}
+
+func TestWebTargetAdminRemoveSelf(t *testing.T) {
+	v := New()
+	defer v.Close()
+	target, err := NewWebTarget("127.0.0.1:0", WithAdmin(v, "secret", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.AddTarget(target); err != nil {
+		t.Fatal(err)
+	}
+	if err := target.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, target.URL()+"/admin/targets/"+target.Name(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusConflict)
+	}
+	if len(v.Targets()) != 1 {
+		t.Fatal("serving target was removed")
+	}
+}
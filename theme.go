@@ -0,0 +1,146 @@
+package nimsforestviewer
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"sync/atomic"
+)
+
+// Theme is the color palette of rendered frames.
+type Theme struct {
+	Background color.RGBA
+	Land       color.RGBA
+	Manaland   color.RGBA
+	// Processes holds the marker color per process type: "tree", "treehouse" and "nim".
+	// Types without an entry keep the renderer's color.
+	Processes map[string]color.RGBA
+	// Failed is the color of the outline drawn around lands with a failed process.
+	// Zero uses DarkTheme.Failed.
+	Failed color.RGBA
+}
+
+// DarkTheme is the sprite renderer's built-in palette.
+var DarkTheme = Theme{
+	Background: color.RGBA{20, 25, 30, 255},
+	Land:       color.RGBA{60, 70, 60, 255},
+	Manaland:   color.RGBA{80, 60, 120, 255},
+	Processes: map[string]color.RGBA{
+		"tree":      {60, 150, 60, 255},
+		"treehouse": {150, 150, 150, 255},
+		"nim":       {200, 180, 100, 255},
+	},
+	Failed: color.RGBA{220, 50, 50, 255},
+}
+
+// LightTheme is a high-key palette for displays in bright rooms.
+var LightTheme = Theme{
+	Background: color.RGBA{236, 239, 241, 255},
+	Land:       color.RGBA{196, 214, 198, 255},
+	Manaland:   color.RGBA{206, 190, 232, 255},
+	Processes: map[string]color.RGBA{
+		"tree":      {36, 122, 58, 255},
+		"treehouse": {96, 104, 120, 255},
+		"nim":       {196, 128, 20, 255},
+	},
+	Failed: color.RGBA{200, 30, 30, 255},
+}
+
+// ThemedTarget is implemented by targets that render images and can change their palette.
+// A Viewer created with WithTheme applies its theme to every ThemedTarget it is given.
+type ThemedTarget interface {
+	Target
+	SetTheme(theme Theme)
+}
+
+// themer recolors rendered frames. Image-producing targets embed it to implement ThemedTarget.
+type themer struct {
+	remap  atomic.Pointer[map[uint32]color.RGBA]
+	failed atomic.Pointer[color.RGBA] // Outline color of failed cells; nil uses DarkTheme.Failed
+}
+
+// SetTheme sets the palette of subsequently rendered frames.
+func (t *themer) SetTheme(theme Theme) {
+	// The sprite renderer always draws DarkTheme's colors; map each one to its replacement
+	remap := make(map[uint32]color.RGBA)
+	add := func(from, to color.RGBA) {
+		if from != to {
+			remap[rgbKey(from)] = to
+		}
+	}
+	add(DarkTheme.Background, theme.Background)
+	add(DarkTheme.Land, theme.Land)
+	add(DarkTheme.Manaland, theme.Manaland)
+	for procType, from := range DarkTheme.Processes {
+		if to, ok := theme.Processes[procType]; ok {
+			add(from, to)
+		}
+	}
+	failed := theme.Failed
+	if failed == (color.RGBA{}) {
+		failed = DarkTheme.Failed
+	}
+	t.remap.Store(&remap)
+	t.failed.Store(&failed)
+}
+
+// recolor applies the theme to a frame from the sprite renderer and outlines
+// the failed cells, as from failedCells, in Theme.Failed.
+// Alpha is preserved, so the renderer's pulse animation still shows.
+// Without a theme or failed cells, the frame is returned unchanged.
+func (t *themer) recolor(img image.Image, failed []image.Rectangle) image.Image {
+	remap := t.remap.Load()
+	if remap == nil && len(failed) == 0 {
+		return img
+	}
+
+	rgba := ensureRGBA(img)
+	if remap != nil {
+		pix := rgba.Pix
+		for i := 0; i+3 < len(pix); i += 4 {
+			c, ok := (*remap)[uint32(pix[i])<<16|uint32(pix[i+1])<<8|uint32(pix[i+2])]
+			if ok {
+				pix[i], pix[i+1], pix[i+2] = c.R, c.G, c.B
+			}
+		}
+	}
+	if len(failed) > 0 {
+		c := DarkTheme.Failed
+		if p := t.failed.Load(); p != nil {
+			c = *p
+		}
+		outlineRects(rgba, failed, c)
+	}
+	return rgba
+}
+
+// outlineRects draws a border just inside each of rects, a sixteenth of its
+// width thick but at least 2 pixels.
+func outlineRects(img *image.RGBA, rects []image.Rectangle, c color.RGBA) {
+	src := image.NewUniform(c)
+	for _, r := range rects {
+		w := max(r.Dx()/16, 2)
+		for _, edge := range []image.Rectangle{
+			{r.Min, image.Pt(r.Max.X, r.Min.Y+w)},
+			{image.Pt(r.Min.X, r.Max.Y-w), r.Max},
+			{r.Min, image.Pt(r.Min.X+w, r.Max.Y)},
+			{image.Pt(r.Max.X-w, r.Min.Y), r.Max},
+		} {
+			draw.Draw(img, edge.Intersect(img.Bounds()), src, image.Point{}, draw.Src)
+		}
+	}
+}
+
+// background returns the theme's background color, for padding around frames.
+func (t *themer) background() color.RGBA {
+	if remap := t.remap.Load(); remap != nil {
+		if c, ok := (*remap)[rgbKey(DarkTheme.Background)]; ok {
+			return c
+		}
+	}
+	return DarkTheme.Background
+}
+
+func rgbKey(c color.RGBA) uint32 {
+	return uint32(c.R)<<16 | uint32(c.G)<<8 | uint32(c.B)
+}
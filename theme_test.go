@@ -0,0 +1,52 @@
+package nimsforestviewer
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	sprites "github.com/nimsforest/nimsforestsprites"
+)
+
+func TestRecolorOutlinesFailedCellsOnly(t *testing.T) {
+	opts := sprites.Options{Width: 400, Height: 300}
+	state := &ViewState{Lands: []LandView{
+		{ID: "ok", IsManaland: true, Nims: []ProcessView{{ID: "nim", Type: "nim"}}},
+		{ID: "bad", Nims: []ProcessView{{ID: "a", State: ProcessStateFailed}, {ID: "b", State: ProcessStateFailed}}},
+	}}
+	cells := failedCells(state, opts)
+	if len(cells) != 1 {
+		t.Fatalf("failedCells = %v, want one cell for the failed land", cells)
+	}
+	positions := layoutGrid(state.Lands)
+	want := gridRectToPixels(Viewport{X: positions[1].X, Y: positions[1].Y, Width: 1, Height: 1}, opts)
+	if cells[0] != want {
+		t.Fatalf("failed cell = %v, want %v", cells[0], want)
+	}
+
+	// Colors are left alone, including the one failed processes used to be
+	// drawn in, except for the failed cell's outline
+	fill := color.RGBA{150, 100, 200, 255}
+	frame := image.NewRGBA(image.Rect(0, 0, opts.Width, opts.Height))
+	draw.Draw(frame, frame.Bounds(), image.NewUniform(fill), image.Point{}, draw.Src)
+
+	var th themer
+	got := ensureRGBA(th.recolor(frame, cells))
+	if c := got.RGBAAt(want.Min.X, want.Min.Y); c != DarkTheme.Failed {
+		t.Errorf("failed cell corner = %v, want %v", c, DarkTheme.Failed)
+	}
+	if c := got.RGBAAt(want.Min.X+want.Dx()/2, want.Min.Y+want.Dy()/2); c != fill {
+		t.Errorf("failed cell center = %v, want it left as %v", c, fill)
+	}
+	okCell := gridRectToPixels(Viewport{X: positions[0].X, Y: positions[0].Y, Width: 1, Height: 1}, opts)
+	if c := got.RGBAAt(okCell.Min.X, okCell.Min.Y); c != fill {
+		t.Errorf("healthy cell corner = %v, want it left as %v", c, fill)
+	}
+
+	// A themed Failed color replaces the default
+	th.SetTheme(Theme{Failed: color.RGBA{1, 2, 3, 255}})
+	if c := ensureRGBA(th.recolor(frame, cells)).RGBAAt(want.Min.X, want.Min.Y); c != (color.RGBA{1, 2, 3, 255}) {
+		t.Errorf("themed failed outline = %v, want the theme's Failed", c)
+	}
+}
@@ -0,0 +1,42 @@
+package nimsforestviewer
+
+import "fmt"
+
+// Byte units for RAM figures, so providers can write 16 * GiB instead of
+// 16e9 or 16 * 1024 * 1024 * 1024 and leave no doubt about which one is meant.
+const (
+	KB uint64 = 1000
+	MB        = 1000 * KB
+	GB        = 1000 * MB
+	TB        = 1000 * GB
+
+	KiB uint64 = 1024
+	MiB        = 1024 * KiB
+	GiB        = 1024 * MiB
+	TiB        = 1024 * GiB
+)
+
+// Bytes formats a byte count with binary (IEC) units, e.g. "16.0 GiB".
+// The viewer formats RAM this way everywhere.
+func Bytes(n uint64) string {
+	return formatBytes(n, 1024, "iB")
+}
+
+// BytesSI formats a byte count with decimal (SI) units, e.g. "16.0 GB".
+func BytesSI(n uint64) string {
+	return formatBytes(n, 1000, "B")
+}
+
+// formatBytes formats n with one decimal in the largest unit of base that keeps
+// the value at least 1, appending suffix to the unit prefix.
+func formatBytes(n, base uint64, suffix string) string {
+	if n < base {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := base, 0
+	for m := n / base; m >= base; m /= base {
+		div *= base
+		exp++
+	}
+	return fmt.Sprintf("%.1f %c%s", float64(n)/float64(div), "KMGTPE"[exp], suffix)
+}
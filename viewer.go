@@ -2,19 +2,103 @@ package nimsforestviewer
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"image"
+	"image/draw"
+	"math/rand/v2"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Viewer manages visualization output to multiple targets.
 type Viewer struct {
-	mu       sync.RWMutex
-	provider StateProvider
-	targets  []Target
-	interval time.Duration
-	cancel   context.CancelFunc
-	done     chan struct{}
+	mu           sync.RWMutex
+	provider     StateProvider
+	targets      []Target
+	interval     time.Duration
+	jitter       float64       // Fraction of interval each tick is randomized by
+	renderEvery  time.Duration // Re-dispatch the last state this often between updates
+	interpolate  bool          // Tween between states on render ticks
+	tweenFrom    *ViewState    // State the current tween starts from
+	tweenAt      time.Time     // When the current tween started
+	observer     Observer
+	onUpdate     func(*ViewState)
+	onError      func(Target, error)
+	layout       LayoutFunc
+	autoSum      bool   // Recompute Summary from Lands before dispatch
+	theme        *Theme // Applied to ThemedTargets as they are added
+	occWarn      float64
+	occCrit      float64
+	coalesce     bool // Collapse updates queued behind a busy target into the newest one
+	concurrent   bool // Update targets in parallel
+	logger       Logger
+	frames       *SharedRenderer // Renders one frame per update for ImageTargets
+	stableOrder  bool            // Sort lands and processes before layout
+	maxLands     int             // Fold lands beyond this into one tile; 0 is unlimited
+	maxProcs     int             // Fold processes per land beyond this into one; 0 is unlimited
+	failFast     int             // Stop the run loop after this many fully failed updates
+	validate     bool            // Reject states that fail ViewState.Validate
+	failStreak   int             // Consecutive fully failed updates
+	lastFailure  error           // Error of the most recent fully failed update
+	failErr      error           // Why the run loop stopped, if fail-fast stopped it
+	last         *ViewState      // Most recent state fetched from the provider, after layout
+	skipSame     bool            // Skip dispatch when the state hashes the same as last time
+	maxStale     time.Duration   // With skipSame, dispatch anyway once this much time has passed
+	lastHash     [sha256.Size]byte
+	lastDispatch time.Time
+	refreshDelay time.Duration // Debounce window of Refresh
+	refreshTimer *time.Timer   // Pending Refresh update, if any
+	refreshC     chan struct{} // Hands debounced Refresh updates to the run loop
+	paused       bool
+	closed       bool
+	cancel       context.CancelFunc
+	ctx          context.Context // Parent of every update's context; canceled by Close
+	stop         context.CancelFunc
+	done         chan struct{}
+	looping      bool // The run loop was launched; done is closed once it exits
+
+	slotMu sync.Mutex
+	slots  map[Target]*targetSlot // In-flight updates per target, when coalescing
+
+	prepMu    sync.Mutex     // Serializes fetching and preparing states, so v.last only moves forward
+	preparing atomic.Bool    // An update holds prepMu
+	ticks     sync.WaitGroup // Periodic updates the run loop didn't wait for, with coalescing
+}
+
+// targetSlot tracks a target's in-flight update and the newest state waiting behind it.
+type targetSlot struct {
+	pending *ViewState
+}
+
+// Observer is notified after every target update, e.g. to record metrics.
+// Implementations must be safe for concurrent use.
+type Observer interface {
+	// ObserveUpdate reports how long a target's Update took and the error it returned, if any.
+	ObserveUpdate(targetName string, d time.Duration, err error)
+}
+
+// UpdateStats describes one update, as returned by UpdateWithStats.
+type UpdateStats struct {
+	Duration time.Duration // Fetching the state and updating every target
+	Skipped  bool          // The state was unchanged and WithSkipUnchanged skipped dispatch
+	Targets  []TargetStats // In the order the targets were added
+}
+
+// TargetStats describes how one target handled an update.
+type TargetStats struct {
+	Name      string
+	Duration  time.Duration
+	Err       error
+	Coalesced bool // The target was busy, and its in-flight update delivers this state instead
+
+	// Set by targets implementing FrameStatsReporter: the encoded frame size in
+	// bytes, and whether the frame matched the previous one and wasn't sent.
+	FrameSize      int
+	FrameUnchanged bool
 }
 
 // Option configures the Viewer.
@@ -27,12 +111,226 @@ func WithInterval(d time.Duration) Option {
 	}
 }
 
+// WithRenderInterval re-sends the most recent state to the targets every d
+// between periodic updates, so the provider can be polled slowly while image
+// targets keep animating. It has no effect unless d is shorter than the update
+// interval. Re-renders don't count towards WithFailFast.
+func WithRenderInterval(d time.Duration) Option {
+	return func(v *Viewer) {
+		v.renderEvery = d
+	}
+}
+
+// WithInterpolation makes the re-renders of WithRenderInterval tween land
+// occupancy and process progress from the previously displayed state to the
+// latest one over the update interval, so infrequent updates animate smoothly
+// instead of jumping. The display trails the provider by one interval.
+// It has no effect without WithRenderInterval. See InterpolateState.
+func WithInterpolation(enable bool) Option {
+	return func(v *Viewer) {
+		v.interpolate = enable
+	}
+}
+
+// WithJitter randomizes each periodic update within ±fraction of the interval,
+// re-rolled every tick, so several viewers don't hit a backend in lockstep.
+// A fraction of zero keeps exact-interval ticks; values above 1 are capped at 1.
+func WithJitter(fraction float64) Option {
+	return func(v *Viewer) {
+		v.jitter = min(max(fraction, 0), 1)
+	}
+}
+
+// WithObserver sets an Observer that is called around each target update.
+func WithObserver(o Observer) Option {
+	return func(v *Viewer) {
+		v.observer = o
+	}
+}
+
+// WithOnUpdate sets a callback invoked with each freshly fetched state before it is
+// sent to targets. The callback must treat the state as read-only.
+func WithOnUpdate(fn func(*ViewState)) Option {
+	return func(v *Viewer) {
+		v.onUpdate = fn
+	}
+}
+
+// WithOnError sets a callback invoked whenever an update fails. The target is nil
+// when the error came from the state provider.
+func WithOnError(fn func(Target, error)) Option {
+	return func(v *Viewer) {
+		v.onError = fn
+	}
+}
+
+// WithLayout sets a LayoutFunc that rearranges each state's grid positions
+// before it is sent to targets, e.g. PackedLayout to close gaps.
+func WithLayout(fn LayoutFunc) Option {
+	return func(v *Viewer) {
+		v.layout = fn
+	}
+}
+
+// WithAutoSummary recomputes each state's Summary from its Lands before it is
+// sent to targets, so callers don't have to keep the two in sync.
+func WithAutoSummary(enable bool) Option {
+	return func(v *Viewer) {
+		v.autoSum = enable
+	}
+}
+
+// WithTheme sets the palette of every image-producing target added to the viewer,
+// such as SmartTVTarget and VideoTarget. Use DarkTheme, LightTheme, or a custom Theme.
+func WithTheme(theme Theme) Option {
+	return func(v *Viewer) {
+		v.theme = &theme
+	}
+}
+
+// WithOccupancyThresholds sets the occupancy at which a land's status becomes
+// "warn" and "critical". Defaults to DefaultOccupancyWarn and DefaultOccupancyCritical.
+func WithOccupancyThresholds(warn, critical float64) Option {
+	return func(v *Viewer) {
+		v.occWarn = warn
+		v.occCrit = critical
+	}
+}
+
+// WithCoalescing makes updates skip targets that are still busy with an earlier
+// update. The newest skipped state is delivered as soon as the target is free and
+// intermediate states are dropped, so a slow target never falls further behind.
+// The periodic update loop then no longer waits for an update to finish before
+// starting the next, so a slow target doesn't hold back the others: it keeps
+// working through the newest state while the rest are updated on every tick.
+func WithCoalescing(enable bool) Option {
+	return func(v *Viewer) {
+		v.coalesce = enable
+	}
+}
+
+// maxConcurrentUpdates bounds how many targets WithConcurrentUpdates updates at once.
+const maxConcurrentUpdates = 8
+
+// WithConcurrentUpdates updates the targets in parallel, up to 8 at a time,
+// instead of one after another, so a slow TV doesn't delay a fast web target.
+// Update waits for all of them.
+func WithConcurrentUpdates(enable bool) Option {
+	return func(v *Viewer) {
+		v.concurrent = enable
+	}
+}
+
+// WithLogger sets where the viewer logs target changes and update failures.
+// The logger is also passed to every LoggingTarget added. Defaults to NopLogger.
+func WithLogger(l Logger) Option {
+	return func(v *Viewer) {
+		if l == nil {
+			l = NopLogger
+		}
+		v.logger = l
+	}
+}
+
+// WithFrameRenderer renders each update once through r and hands the frame to every
+// ImageTarget, instead of each of them rendering the same state. Targets should be
+// created with the same renderer, e.g. WithSharedRenderer(r), so their viewports
+// match the frame. Targets that aren't ImageTargets are updated as usual.
+func WithFrameRenderer(r *SharedRenderer) Option {
+	return func(v *Viewer) {
+		v.frames = r
+	}
+}
+
+// WithStableOrder sorts lands and processes with SortLands before layout, so the
+// grid, API payloads and change detection don't churn when a provider returns
+// lands in varying order.
+func WithStableOrder(enable bool) Option {
+	return func(v *Viewer) {
+		v.stableOrder = enable
+	}
+}
+
+// WithMaxLands keeps at most n lands by folding the least occupied ones into a
+// single "others" tile with AggregateLands, so frames and payloads stay legible
+// on huge clusters. The summary still counts every land. Zero, the default,
+// keeps all lands.
+func WithMaxLands(n int) Option {
+	return func(v *Viewer) {
+		v.maxLands = n
+	}
+}
+
+// WithMaxProcessesPerLand keeps at most n processes on each land by folding the
+// smallest into a single "+N more" process with LimitProcesses, so tiles of lands
+// running hundreds of processes stay readable. The summary and the JSON
+// process_count still count every process; sprite frames, which can't show the
+// label, draw the marker as one more process. Zero, the default, keeps all processes.
+func WithMaxProcessesPerLand(n int) Option {
+	return func(v *Viewer) {
+		v.maxProcs = n
+	}
+}
+
+// WithRefreshDebounce sets how long Refresh waits before updating, so a burst of
+// calls within d results in a single update. Defaults to 100ms.
+func WithRefreshDebounce(d time.Duration) Option {
+	return func(v *Viewer) {
+		v.refreshDelay = d
+	}
+}
+
+// WithFailFast stops the periodic update loop after n consecutive updates in
+// which the state provider or every target failed. Done is closed and Err
+// reports the last failure, so a supervisor can restart the process instead of
+// leaving a viewer that can't reach anything. Zero, the default, never stops.
+func WithFailFast(n int) Option {
+	return func(v *Viewer) {
+		v.failFast = n
+	}
+}
+
+// WithValidation runs ViewState.Validate on each state from the provider and
+// treats a state that fails it like a provider error: it is reported through
+// OnError and the logger, and nothing is dispatched.
+func WithValidation(enable bool) Option {
+	return func(v *Viewer) {
+		v.validate = enable
+	}
+}
+
+// WithSkipUnchanged skips updating targets when the fetched state is identical to
+// the last dispatched one. Detecting this costs a JSON encode and SHA-256 of the
+// state on every update, which is cheap next to rendering a frame for a TV but
+// can dominate when all targets are lightweight.
+func WithSkipUnchanged(enable bool) Option {
+	return func(v *Viewer) {
+		v.skipSame = enable
+	}
+}
+
+// WithMaxStaleInterval forces an update of all targets at least every d even if the
+// state hasn't changed, for TVs that drop idle sessions. It only has an effect with
+// WithSkipUnchanged. Zero, the default, never forces one.
+func WithMaxStaleInterval(d time.Duration) Option {
+	return func(v *Viewer) {
+		v.maxStale = d
+	}
+}
+
 // New creates a new Viewer with the given options.
 func New(opts ...Option) *Viewer {
 	v := &Viewer{
 		interval: time.Second, // Default 1 second
+		occWarn:  DefaultOccupancyWarn,
+		occCrit:  DefaultOccupancyCritical,
+		logger:   NopLogger,
 		done:     make(chan struct{}),
+
+		refreshDelay: 100 * time.Millisecond,
+		refreshC:     make(chan struct{}, 1),
 	}
+	v.ctx, v.stop = context.WithCancel(context.Background())
 	for _, opt := range opts {
 		opt(v)
 	}
@@ -47,10 +345,31 @@ func (v *Viewer) SetStateProvider(p StateProvider) {
 }
 
 // AddTarget adds an output target.
+// Adding a target that is already present returns an error.
+// If the viewer has a theme and t is a ThemedTarget, the theme is applied to it.
+// If the viewer has a logger and t is a LoggingTarget, it logs there too.
 func (v *Viewer) AddTarget(t Target) error {
 	v.mu.Lock()
 	defer v.mu.Unlock()
+	if v.closed {
+		return fmt.Errorf("viewer closed")
+	}
+	for _, target := range v.targets {
+		if target == t {
+			return fmt.Errorf("target %s already added", t.Name())
+		}
+	}
+	if themed, ok := t.(ThemedTarget); ok && v.theme != nil {
+		themed.SetTheme(*v.theme)
+	}
+	if logging, ok := t.(LoggingTarget); ok && v.logger != NopLogger {
+		logging.SetLogger(v.logger)
+	}
+	if refreshing, ok := t.(RefreshingTarget); ok {
+		refreshing.SetRefresh(v.Refresh)
+	}
 	v.targets = append(v.targets, t)
+	v.logger.Info("target added", "target", t.Name())
 	return nil
 }
 
@@ -61,11 +380,34 @@ func (v *Viewer) RemoveTarget(t Target) {
 	for i, target := range v.targets {
 		if target == t {
 			v.targets = append(v.targets[:i], v.targets[i+1:]...)
+			v.logger.Info("target removed", "target", t.Name())
 			return
 		}
 	}
 }
 
+// RemoveTargetByName removes the first target whose Name is name and returns it,
+// or nil if there is none. Like RemoveTarget, it doesn't close the target.
+func (v *Viewer) RemoveTargetByName(name string) Target {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for i, target := range v.targets {
+		if target.Name() == name {
+			v.targets = append(v.targets[:i], v.targets[i+1:]...)
+			v.logger.Info("target removed", "target", name)
+			return target
+		}
+	}
+	return nil
+}
+
+// Targets returns the targets in the order they were added.
+func (v *Viewer) Targets() []Target {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return append([]Target(nil), v.targets...)
+}
+
 // Start begins periodic updates to all targets.
 func (v *Viewer) Start(ctx context.Context) error {
 	v.mu.Lock()
@@ -78,27 +420,165 @@ func (v *Viewer) Start(ctx context.Context) error {
 	v.mu.Unlock()
 
 	// Initial update
-	if err := v.Update(); err != nil {
+	if err := v.UpdateContext(ctx); err != nil {
 		return err
 	}
 
+	v.mu.Lock()
+	v.looping = true
+	v.mu.Unlock()
 	go v.run(ctx)
 	return nil
 }
 
 func (v *Viewer) run(ctx context.Context) {
-	ticker := time.NewTicker(v.interval)
-	defer ticker.Stop()
 	defer close(v.done)
+	defer v.ticks.Wait()
+
+	var renderC <-chan time.Time // Nil, and never ready, without a render interval
+	if v.renderEvery > 0 && v.renderEvery < v.interval {
+		renderTicker := time.NewTicker(v.renderEvery)
+		defer renderTicker.Stop()
+		renderC = renderTicker.C
+	}
+
+	if v.jitter <= 0 {
+		ticker := time.NewTicker(v.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if !v.tick(ctx) {
+					return
+				}
+			case <-v.refreshC:
+				if !v.tick(ctx) {
+					return
+				}
+			case <-renderC:
+				v.renderTick(ctx)
+			}
+		}
+	}
+
+	timer := time.NewTimer(v.nextInterval())
+	defer timer.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
-			_ = v.Update() // Ignore errors in background loop
+		case <-timer.C:
+			if !v.tick(ctx) {
+				return
+			}
+			timer.Reset(v.nextInterval())
+		case <-v.refreshC:
+			if !v.tick(ctx) {
+				return
+			}
+		case <-renderC:
+			v.renderTick(ctx)
+		}
+	}
+}
+
+// tick runs a periodic or Refresh update unless the viewer is paused. It returns false
+// when WithFailFast says the loop should stop. With coalescing, the update runs
+// in the background; targets still busy with an earlier one are skipped by it.
+func (v *Viewer) tick(ctx context.Context) bool {
+	v.mu.RLock()
+	paused, coalesce := v.paused, v.coalesce
+	v.mu.RUnlock()
+	if paused {
+		return true
+	}
+	if coalesce {
+		// Fail-fast then judges the updates that finished before this tick
+		if v.failedOut() {
+			return false
+		}
+		if v.preparing.Load() {
+			return true // An earlier update is still fetching; it gets the newest state anyway
 		}
+		v.ticks.Add(1)
+		go func() {
+			defer v.ticks.Done()
+			_ = v.UpdateContext(ctx) // Errors are reported through onError and the logger
+		}()
+		return true
+	}
+	_ = v.UpdateContext(ctx) // Errors are reported through onError and the logger
+	return !v.failedOut()
+}
+
+// failedOut reports whether WithFailFast's limit of consecutive failed updates
+// has been reached, recording why in failErr.
+func (v *Viewer) failedOut() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.failFast > 0 && v.failStreak >= v.failFast {
+		v.failErr = fmt.Errorf("%d consecutive updates failed: %w", v.failStreak, v.lastFailure)
+		v.logger.Error("stopping after consecutive failed updates", "count", v.failStreak, "error", v.lastFailure)
+		return true
+	}
+	return false
+}
+
+// renderTick re-dispatches the most recent state unless the viewer is paused
+// or has no state yet.
+func (v *Viewer) renderTick(ctx context.Context) {
+	if v.Paused() {
+		return
+	}
+	v.mu.RLock()
+	state := v.displayedLocked(time.Now())
+	v.mu.RUnlock()
+	if state == nil {
+		return
+	}
+	v.dispatch(ctx, state, nil) // Errors are reported through onError and the logger
+}
+
+// displayedLocked returns a copy of the state to display at now: the latest
+// state, or with WithInterpolation, the point the tween towards it has reached.
+// v.mu must be held.
+func (v *Viewer) displayedLocked(now time.Time) *ViewState {
+	if v.tweenFrom == nil || v.interval <= 0 {
+		return v.last.Clone()
 	}
+	return InterpolateState(v.tweenFrom, v.last, float64(now.Sub(v.tweenAt))/float64(v.interval))
+}
+
+// nextInterval returns the interval randomized by the configured jitter.
+func (v *Viewer) nextInterval() time.Duration {
+	spread := v.jitter * (2*rand.Float64() - 1)
+	return time.Duration(float64(v.interval) * (1 + spread))
+}
+
+// Pause suspends periodic updates while keeping the update loop and targets alive.
+// Explicit calls to Update still run, e.g. to single-step while paused.
+func (v *Viewer) Pause() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.paused = true
+}
+
+// Resume restarts periodic updates after Pause.
+func (v *Viewer) Resume() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.paused = false
+}
+
+// Paused reports whether periodic updates are paused.
+func (v *Viewer) Paused() bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.paused
 }
 
 // Stop stops periodic updates.
@@ -114,49 +594,500 @@ func (v *Viewer) Stop() {
 	<-v.done
 }
 
+// Refresh schedules an update outside the regular interval, e.g. when the
+// provider signals a change. Calls within the debounce window of WithRefreshDebounce
+// share one update; calls while it runs schedule another. The update runs on the
+// update loop, so it never overlaps a periodic one, and like those it is skipped
+// while paused and before Start. Refresh returns immediately, and failures are
+// reported through WithOnError and the logger.
+func (v *Viewer) Refresh() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.closed || v.paused || v.refreshTimer != nil {
+		return
+	}
+	v.refreshTimer = time.AfterFunc(v.refreshDelay, func() {
+		v.mu.Lock()
+		v.refreshTimer = nil
+		running := v.cancel != nil && !v.closed
+		v.mu.Unlock()
+		if !running {
+			return
+		}
+		select {
+		case v.refreshC <- struct{}{}:
+		default: // One is already queued
+		}
+	})
+}
+
 // Update triggers an immediate update to all targets.
+// If targets fail, their errors are joined, each prefixed with the target's name.
+// It can be interrupted only by Close; use UpdateContext to bound it.
 func (v *Viewer) Update() error {
+	return v.UpdateContext(context.Background())
+}
+
+// UpdateContext triggers an immediate update to all targets, passing them a
+// context that is canceled when ctx is or when the viewer is closed.
+func (v *Viewer) UpdateContext(ctx context.Context) error {
+	return v.update(ctx, nil)
+}
+
+// UpdateWithStats is like Update, but also reports how each target fared.
+func (v *Viewer) UpdateWithStats() (UpdateStats, error) {
+	return v.UpdateWithStatsContext(context.Background())
+}
+
+// UpdateWithStatsContext is like UpdateContext, but also reports how each target fared.
+// When the state provider fails, the stats list no targets.
+func (v *Viewer) UpdateWithStatsContext(ctx context.Context) (UpdateStats, error) {
+	var stats UpdateStats
+	start := time.Now()
+	err := v.update(ctx, &stats)
+	stats.Duration = time.Since(start)
+	return stats, err
+}
+
+// update implements UpdateContext, filling in stats if it is non-nil.
+func (v *Viewer) update(ctx context.Context, stats *UpdateStats) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer context.AfterFunc(v.ctx, cancel)()
+
 	v.mu.RLock()
 	provider := v.provider
-	targets := make([]Target, len(v.targets))
-	copy(targets, v.targets)
+	onUpdate := v.onUpdate
+	onError := v.onError
+	logger := v.logger
+	layout := v.layout
+	autoSum := v.autoSum
+	stableOrder := v.stableOrder
+	maxLands := v.maxLands
+	maxProcs := v.maxProcs
+	validate := v.validate
+	occWarn, occCrit := v.occWarn, v.occCrit
 	v.mu.RUnlock()
 
 	if provider == nil {
 		return fmt.Errorf("no state provider set")
 	}
 
-	state, err := provider.GetViewState()
+	v.prepMu.Lock()
+	v.preparing.Store(true)
+	prepared := func() {
+		v.preparing.Store(false)
+		v.prepMu.Unlock()
+	}
+
+	state, err := getViewState(ctx, provider)
+	if err == nil && validate {
+		if verr := state.Validate(); verr != nil {
+			err = fmt.Errorf("invalid state: %w", verr)
+		}
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to get view state: %w", err)
+		logger.Error("state provider failed", "error", err)
+		if onError != nil {
+			safeCall(func() { onError(nil, err) })
+		}
+		prepared()
+		v.recordOutcome(true, err)
+		return err
+	}
+
+	// Work on a private copy so layout and summary changes never touch the provider's state
+	state = state.Clone()
+	if n := state.Sanitize(); n > 0 {
+		logger.Warn("clamped out-of-range occupancy or progress to 0-1", "count", n)
+	}
+
+	if stableOrder {
+		SortLands(state)
+	}
+	if maxLands > 0 && state != nil && len(state.Lands) > maxLands {
+		if autoSum {
+			state.RecomputeSummary()
+			autoSum = false // Keep the totals of all lands, not of the folded ones
+		}
+		AggregateLands(state, maxLands)
+	}
+	if maxProcs > 0 && state != nil {
+		if autoSum {
+			state.RecomputeSummary()
+			autoSum = false // Count the folded processes too
+		}
+		LimitProcesses(state, maxProcs)
+	}
+	if layout != nil && state != nil {
+		layout(state)
+	}
+	if autoSum && state != nil {
+		state.RecomputeSummary()
+	}
+	if state != nil {
+		for i := range state.Lands {
+			land := &state.Lands[i]
+			land.Status = OccupancyStatus(land.Occupancy, occWarn, occCrit)
+		}
+	}
+
+	v.mu.Lock()
+	dispatchState := state
+	if v.interpolate && v.renderEvery > 0 {
+		// Start the next tween from whatever is on screen now, so nothing jumps
+		now := time.Now()
+		if from := v.displayedLocked(now); from != nil {
+			v.tweenFrom, v.tweenAt = from, now
+			dispatchState = from
+		}
+	}
+	v.last = state.Clone()
+	skip := v.skipUnchanged(state)
+	v.mu.Unlock()
+	prepared()
+
+	if onUpdate != nil {
+		safeCall(func() { onUpdate(state) })
+	}
+
+	if skip {
+		if stats != nil {
+			stats.Skipped = true
+		}
+		return nil
+	}
+	allFailed, err := v.dispatch(ctx, dispatchState, stats)
+	v.recordOutcome(allFailed, err)
+	return err
+}
+
+// dispatch sends state to every target. It reports whether every target failed,
+// and returns every target's error joined. If stats is non-nil, each target's outcome is
+// appended to it.
+func (v *Viewer) dispatch(ctx context.Context, state *ViewState, stats *UpdateStats) (allFailed bool, err error) {
+	v.mu.RLock()
+	observer := v.observer
+	onError := v.onError
+	logger := v.logger
+	coalesce := v.coalesce
+	concurrent := v.concurrent
+	frames := v.frames
+	targets := make([]Target, len(v.targets))
+	copy(targets, v.targets)
+	v.mu.RUnlock()
+
+	// Render once for all image targets
+	var frame image.Image
+	var frameFailed []image.Rectangle
+	if frames != nil && state != nil && hasImageTarget(targets) {
+		frame = frames.Render(NewSpritesStateAdapter(state))
+		frameFailed = failedCells(state, frames.Options())
+	}
+
+	type outcome struct {
+		stats  TargetStats
+		failed bool
+		err    error // The target's last error, with its name
+	}
+	outcomes := make([]outcome, len(targets))
+	deliver := func(i int) {
+		target, out := targets[i], &outcomes[i]
+		// Each target gets its own copy, so one target mutating it can't affect the others
+		targetState := state.Clone()
+		targetFrame := frame
+		if coalesce && !v.beginUpdate(target, targetState) {
+			// The in-flight update delivers this state when it finishes
+			out.stats = TargetStats{Name: target.Name(), Coalesced: true}
+			return
+		}
+		first := true
+		for targetState != nil {
+			d, err := updateTarget(ctx, target, targetState, targetFrame, frameFailed, observer, onError, logger)
+			if err != nil {
+				out.err = fmt.Errorf("target %s: %w", target.Name(), err)
+				out.failed = true
+			}
+			if first {
+				out.stats = targetStats(target, d, err)
+			}
+			first = false
+			if !coalesce {
+				break
+			}
+			targetState = v.finishUpdate(target)
+			targetFrame = nil // Newer states are rendered by the target itself
+		}
+	}
+
+	if concurrent && len(targets) > 1 {
+		sem := make(chan struct{}, maxConcurrentUpdates)
+		var wg sync.WaitGroup
+		for i := range targets {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				deliver(i)
+			}()
+		}
+		wg.Wait()
+	} else {
+		for i := range targets {
+			deliver(i)
+		}
+	}
+
+	var errs []error
+	failed := 0
+	for _, out := range outcomes {
+		if stats != nil {
+			stats.Targets = append(stats.Targets, out.stats)
+		}
+		if out.failed {
+			failed++
+		}
+		if out.err != nil {
+			errs = append(errs, out.err)
+		}
+	}
+	return len(targets) > 0 && failed == len(targets), errors.Join(errs...)
+}
+
+// recordOutcome tracks consecutive fully failed updates for WithFailFast.
+func (v *Viewer) recordOutcome(allFailed bool, err error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if allFailed {
+		v.failStreak++
+		v.lastFailure = err
+	} else {
+		v.failStreak = 0
+	}
+}
+
+// Done returns a channel that is closed when the periodic update loop started by
+// Start exits, whether through Stop, its context, Close or WithFailFast.
+func (v *Viewer) Done() <-chan struct{} {
+	return v.done
+}
+
+// Err returns why WithFailFast stopped the update loop, or nil.
+func (v *Viewer) Err() error {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.failErr
+}
+
+// hasImageTarget reports whether any target is an ImageTarget.
+func hasImageTarget(targets []Target) bool {
+	for _, target := range targets {
+		if _, ok := target.(ImageTarget); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// targetStats describes the update of target that took d and returned err.
+func targetStats(target Target, d time.Duration, err error) TargetStats {
+	s := TargetStats{Name: target.Name(), Duration: d, Err: err}
+	if reporter, ok := target.(FrameStatsReporter); ok && err == nil {
+		s.FrameSize, s.FrameUnchanged = reporter.LastFrameStats()
+	}
+	return s
+}
+
+// updateTarget sends state, or frame if it is non-nil and target is an ImageTarget,
+// to one target and reports how long it took and the target's error. failed
+// holds the frame's cells with failed processes, as from failedCells.
+func updateTarget(ctx context.Context, target Target, state *ViewState, frame image.Image, failed []image.Rectangle, observer Observer, onError func(Target, error), logger Logger) (time.Duration, error) {
+	start := time.Now()
+	var err error
+	if imageTarget, ok := target.(stateImageTarget); ok && frame != nil {
+		err = imageTarget.updateImage(ctx, cloneImage(frame), failed)
+	} else if imageTarget, ok := target.(ImageTarget); ok && frame != nil {
+		err = imageTarget.UpdateImage(ctx, cloneImage(frame))
+	} else {
+		err = target.Update(ctx, state)
+	}
+	d := time.Since(start)
+	if observer != nil {
+		observer.ObserveUpdate(target.Name(), d, err)
+	}
+	if err == nil {
+		return d, nil
+	}
+	logger.Warn("target update failed", "target", target.Name(), "error", err)
+	if onError != nil {
+		safeCall(func() { onError(target, err) })
+	}
+	return d, err
+}
+
+// skipUnchanged reports whether state matches the last dispatched state closely enough
+// to skip dispatching it, and otherwise records it as dispatched. Must be called with v.mu held.
+func (v *Viewer) skipUnchanged(state *ViewState) bool {
+	if !v.skipSame {
+		return false
+	}
+	data, err := json.Marshal(state)
 	if err != nil {
-		return fmt.Errorf("failed to get view state: %w", err)
+		return false
 	}
+	hash := sha256.Sum256(data)
+	now := time.Now()
+	fresh := v.maxStale <= 0 || now.Sub(v.lastDispatch) < v.maxStale
+	if hash == v.lastHash && !v.lastDispatch.IsZero() && fresh {
+		return true
+	}
+	v.lastHash = hash
+	v.lastDispatch = now
+	return false
+}
+
+// LastState returns a copy of the most recent state fetched by Update, as dispatched
+// to targets, or nil before the first successful fetch.
+func (v *Viewer) LastState() *ViewState {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.last.Clone()
+}
+
+// Health reports the health of every target, keyed by name.
+// Targets that don't implement HealthChecker are reported healthy (nil).
+func (v *Viewer) Health() map[string]error {
+	v.mu.RLock()
+	targets := make([]Target, len(v.targets))
+	copy(targets, v.targets)
+	v.mu.RUnlock()
 
-	ctx := context.Background()
-	var lastErr error
+	health := make(map[string]error, len(targets))
 	for _, target := range targets {
-		if err := target.Update(ctx, state); err != nil {
-			lastErr = fmt.Errorf("target %s: %w", target.Name(), err)
+		var err error
+		if checker, ok := target.(HealthChecker); ok {
+			err = checker.Health()
+		}
+		health[target.Name()] = err
+	}
+	return health
+}
+
+// ValidateTargets runs Validate on every target concurrently, keyed by name, and
+// returns the results without sending any state. Targets that don't implement
+// Validator are reported valid (nil).
+func (v *Viewer) ValidateTargets(ctx context.Context) map[string]error {
+	v.mu.RLock()
+	targets := make([]Target, len(v.targets))
+	copy(targets, v.targets)
+	v.mu.RUnlock()
+
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		validator, ok := target.(Validator)
+		if !ok {
+			continue
 		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = validator.Validate(ctx)
+		}()
 	}
-	return lastErr
+	wg.Wait()
+
+	results := make(map[string]error, len(targets))
+	for i, target := range targets {
+		results[target.Name()] = errs[i]
+	}
+	return results
+}
+
+// beginUpdate marks target as busy and reports whether the caller should update it.
+// If the target is already busy, state replaces any state waiting for it.
+func (v *Viewer) beginUpdate(target Target, state *ViewState) bool {
+	v.slotMu.Lock()
+	defer v.slotMu.Unlock()
+	if slot, busy := v.slots[target]; busy {
+		slot.pending = state
+		return false
+	}
+	if v.slots == nil {
+		v.slots = make(map[Target]*targetSlot)
+	}
+	v.slots[target] = &targetSlot{}
+	return true
+}
+
+// finishUpdate returns the newest state that arrived while target was busy,
+// or marks the target free and returns nil.
+func (v *Viewer) finishUpdate(target Target) *ViewState {
+	v.slotMu.Lock()
+	defer v.slotMu.Unlock()
+	slot := v.slots[target]
+	if slot != nil && slot.pending != nil {
+		state := slot.pending
+		slot.pending = nil
+		return state
+	}
+	delete(v.slots, target)
+	return nil
 }
 
-// Close stops the viewer and closes all targets.
+// cloneImage returns an RGBA copy of img that can be modified without affecting img.
+func cloneImage(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(bounds)
+	draw.Draw(dst, bounds, img, bounds.Min, draw.Src)
+	return dst
+}
+
+// safeCall runs a user callback, recovering from panics so a faulty hook
+// can't take down the update loop.
+func safeCall(fn func()) {
+	defer func() {
+		_ = recover()
+	}()
+	fn()
+}
+
+// Close stops the viewer and closes all targets, returning every close error joined.
+// Targets are closed once the update loop and the updates it started have returned.
+// Calling Close more than once is a no-op.
 func (v *Viewer) Close() error {
 	v.mu.Lock()
+	if v.closed {
+		v.mu.Unlock()
+		return nil
+	}
+	v.closed = true
+	v.stop() // Interrupt any update in progress
+	if v.refreshTimer != nil {
+		v.refreshTimer.Stop()
+	}
 	if v.cancel != nil {
 		v.cancel()
 		v.cancel = nil
 	}
 	targets := v.targets
 	v.targets = nil
+	looping := v.looping
 	v.mu.Unlock()
 
-	var lastErr error
+	// Don't close a target while an update is still using it
+	if looping {
+		<-v.done
+	}
+	v.ticks.Wait()
+
+	var errs []error
 	for _, target := range targets {
 		if err := target.Close(); err != nil {
-			lastErr = err
+			errs = append(errs, fmt.Errorf("close %s: %w", target.Name(), err))
 		}
 	}
-	return lastErr
+	return errors.Join(errs...)
 }
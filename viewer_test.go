@@ -0,0 +1,195 @@
+package nimsforestviewer
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it holds or the test times out.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestViewerRefresh(t *testing.T) {
+	rec := NewRecordingTarget("rec")
+	v := New(WithInterval(time.Hour), WithRefreshDebounce(10*time.Millisecond))
+	v.SetStateProvider(NewStaticStateProvider(&ViewState{}))
+	if err := v.AddTarget(rec); err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+
+	// Before Start there is no update loop to run the refresh on
+	v.Refresh()
+	time.Sleep(50 * time.Millisecond)
+	if n := rec.UpdateCount(); n != 0 {
+		t.Fatalf("updates before Start = %d, want 0", n)
+	}
+
+	if err := v.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := rec.UpdateCount(); n != 1 {
+		t.Fatalf("updates after Start = %d, want 1", n)
+	}
+
+	// A burst of calls results in one update
+	for i := 0; i < 5; i++ {
+		v.Refresh()
+	}
+	waitFor(t, func() bool { return rec.UpdateCount() == 2 })
+	time.Sleep(50 * time.Millisecond)
+	if n := rec.UpdateCount(); n != 2 {
+		t.Fatalf("updates after a burst = %d, want 2", n)
+	}
+
+	// Paused viewers ignore refreshes
+	v.Pause()
+	v.Refresh()
+	time.Sleep(50 * time.Millisecond)
+	if n := rec.UpdateCount(); n != 2 {
+		t.Fatalf("updates while paused = %d, want 2", n)
+	}
+}
+
+// mutatingTarget records states like RecordingTarget, but first scribbles over
+// the state it was given, as a careless target might.
+type mutatingTarget struct {
+	*RecordingTarget
+}
+
+func (t mutatingTarget) Update(ctx context.Context, state *ViewState) error {
+	for i := range state.Lands {
+		land := &state.Lands[i]
+		land.Hostname = t.Name()
+		land.Trees[0].Name = t.Name()
+		land.Trees[0].Subjects[0] = t.Name()
+		land.Nims = append(land.Nims, ProcessView{ID: t.Name()})
+	}
+	state.Lands = append(state.Lands, LandView{ID: t.Name()})
+	return t.RecordingTarget.Update(ctx, state)
+}
+
+func TestViewerTargetsGetOwnStateCopies(t *testing.T) {
+	state := &ViewState{Lands: []LandView{{
+		ID:       "land-1",
+		Hostname: "host-1",
+		Trees:    []ProcessView{{ID: "tree-1", Name: "tree", Subjects: []string{"subject"}}},
+	}}}
+	a := mutatingTarget{NewRecordingTarget("a")}
+	b := mutatingTarget{NewRecordingTarget("b")}
+	v := New(WithConcurrentUpdates(true))
+	v.SetStateProvider(NewStaticStateProvider(state))
+	for _, target := range []Target{a, b} {
+		if err := v.AddTarget(target); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer v.Close()
+
+	// Run with -race: the targets mutate their states in parallel
+	for i := 0; i < 10; i++ {
+		if err := v.Update(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, target := range []mutatingTarget{a, b} {
+		got := target.LastState()
+		if len(got.Lands) != 2 || got.Lands[0].Hostname != target.Name() || len(got.Lands[0].Nims) != 1 {
+			t.Errorf("target %s saw another target's changes: %+v", target.Name(), got.Lands)
+		}
+	}
+	if land := state.Lands[0]; land.Hostname != "host-1" || land.Trees[0].Name != "tree" ||
+		land.Trees[0].Subjects[0] != "subject" || len(land.Nims) != 0 || len(state.Lands) != 1 {
+		t.Errorf("provider state was mutated: %+v", state.Lands)
+	}
+	if last := v.LastState(); last.Lands[0].Hostname != "host-1" || len(last.Lands) != 1 {
+		t.Errorf("viewer state was mutated: %+v", last.Lands)
+	}
+}
+
+// slowTarget records states like RecordingTarget, taking delay for each update.
+type slowTarget struct {
+	*RecordingTarget
+	delay time.Duration
+}
+
+func (t slowTarget) Update(ctx context.Context, state *ViewState) error {
+	time.Sleep(t.delay)
+	return t.RecordingTarget.Update(ctx, state)
+}
+
+func TestViewerCoalescingKeepsFastTargetsUpdating(t *testing.T) {
+	slow := slowTarget{NewRecordingTarget("slow"), 200 * time.Millisecond}
+	fast := NewRecordingTarget("fast")
+	v := New(WithInterval(10*time.Millisecond), WithCoalescing(true))
+	v.SetStateProvider(NewStaticStateProvider(&ViewState{}))
+	for _, target := range []Target{slow, fast} {
+		if err := v.AddTarget(target); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer v.Close()
+
+	if err := v.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// The fast target keeps getting ticks while the slow one works through its backlog
+	waitFor(t, func() bool { return fast.UpdateCount() >= 10 })
+	if n := slow.UpdateCount(); n > 3 {
+		t.Errorf("slow target got %d updates, want intermediate states dropped", n)
+	}
+	v.Stop()
+}
+
+// closeCheckTarget is a slow target that records being closed mid-update.
+type closeCheckTarget struct {
+	*RecordingTarget
+	inFlight    atomic.Int32
+	closedEarly atomic.Bool
+}
+
+func (t *closeCheckTarget) Update(ctx context.Context, state *ViewState) error {
+	t.inFlight.Add(1)
+	defer t.inFlight.Add(-1)
+	time.Sleep(100 * time.Millisecond) // Ignores ctx, like a blocking send
+	return t.RecordingTarget.Update(ctx, state)
+}
+
+func (t *closeCheckTarget) Close() error {
+	if t.inFlight.Load() > 0 {
+		t.closedEarly.Store(true)
+	}
+	return t.RecordingTarget.Close()
+}
+
+func TestViewerCloseWaitsForUpdates(t *testing.T) {
+	for _, coalesce := range []bool{false, true} {
+		target := &closeCheckTarget{RecordingTarget: NewRecordingTarget("slow")}
+		v := New(WithInterval(10*time.Millisecond), WithCoalescing(coalesce))
+		v.SetStateProvider(NewStaticStateProvider(&ViewState{}))
+		if err := v.AddTarget(target); err != nil {
+			t.Fatal(err)
+		}
+		if err := v.Start(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		waitFor(t, func() bool { return target.inFlight.Load() > 0 })
+		if err := v.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if target.closedEarly.Load() {
+			t.Errorf("coalescing %v: target closed during an update", coalesce)
+		}
+	}
+}
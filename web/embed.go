@@ -0,0 +1,54 @@
+// Package web contains the interactive frontend embedded into WebTarget.
+package web
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"io/fs"
+	"maps"
+)
+
+//go:embed static
+var static embed.FS
+
+// FS returns the embedded frontend assets rooted at the static directory.
+func FS() fs.FS {
+	sub, err := fs.Sub(static, "static")
+	if err != nil {
+		// The static directory is embedded at compile time, so this cannot fail.
+		panic(err)
+	}
+	return sub
+}
+
+// etags holds the ETag of every embedded asset, hashed at package init so the
+// first request doesn't pay for it.
+var etags = hashAssets()
+
+// ETags returns a strong ETag for every embedded asset, keyed by its path
+// relative to the static directory. The embedded files carry no modification
+// times, so these are what lets browsers revalidate them. They are hashed from
+// the compiled-in content when the package is initialized; each call returns a
+// new copy of the map.
+func ETags() map[string]string {
+	return maps.Clone(etags)
+}
+
+// hashAssets computes the ETag of every embedded asset.
+func hashAssets() map[string]string {
+	etags := make(map[string]string)
+	fs.WalkDir(FS(), ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(FS(), name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		etags[name] = `"` + hex.EncodeToString(sum[:16]) + `"`
+		return nil
+	})
+	return etags
+}
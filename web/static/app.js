@@ -0,0 +1,339 @@
+// nimsforestviewer interactive frontend.
+// Polls /api/viewmodel and draws lands on their grid with occupancy and process progress.
+(function () {
+    "use strict";
+
+    const POLL_INTERVAL_MS = 2000;
+    const SCHEMA_VERSION = 7; // Must match nimsforestviewer.SchemaVersion
+    const MORE_PROCESSES_ID = "_more"; // nimsforestviewer.MoreProcessesID
+    const TILE = 140;
+    const GAP = 12;
+    const PADDING = 24;
+
+    const COLORS = {
+        land: "#2f3b36",
+        mana: "#4b3a78",
+        border: "#3f4f49",
+        occupancyLow: "#4ade80",
+        occupancyMid: "#facc15",
+        occupancyHigh: "#f87171",
+        tree: "#4ade80",
+        treehouse: "#facc15",
+        nim: "#60a5fa",
+        failed: "#ef4444",
+        edge: "rgba(96, 165, 250, 0.5)",
+        text: "#e5e7eb",
+        muted: "#9ca3af",
+    };
+
+    const canvas = document.getElementById("forest");
+    const ctx = canvas.getContext("2d");
+    const tooltip = document.getElementById("tooltip");
+    const summaryEl = document.getElementById("summary");
+    const statusEl = document.getElementById("status");
+
+    let world = { lands: [], summary: {} };
+    let hitboxes = [];
+    let live = false; // Connected with a supported payload; the status shows the data age
+
+    // formatBytes uses binary units like the server's Bytes, so figures match the summary.
+    function formatBytes(bytes) {
+        if (!bytes) return "0 B";
+        const units = ["B", "KiB", "MiB", "GiB", "TiB"];
+        let i = 0;
+        let value = bytes;
+        while (value >= 1024 && i < units.length - 1) {
+            value /= 1024;
+            i++;
+        }
+        return value.toFixed(value < 10 && i > 0 ? 1 : 0) + " " + units[i];
+    }
+
+    // Status thresholds are configured on the server; fall back to its defaults for older servers.
+    function landStatus(land) {
+        if (land.status) return land.status;
+        const occupancy = land.occupancy || 0;
+        if (occupancy >= 0.85) return "critical";
+        if (occupancy >= 0.6) return "warn";
+        return "ok";
+    }
+
+    function statusColor(status) {
+        if (status === "critical") return COLORS.occupancyHigh;
+        if (status === "warn") return COLORS.occupancyMid;
+        return COLORS.occupancyLow;
+    }
+
+    function resize() {
+        const ratio = window.devicePixelRatio || 1;
+        const rect = canvas.getBoundingClientRect();
+        canvas.width = Math.floor(rect.width * ratio);
+        canvas.height = Math.floor(rect.height * ratio);
+        ctx.setTransform(ratio, 0, 0, ratio, 0, 0);
+        draw();
+    }
+
+    // landProcesses lists a land's processes with each tree followed by the
+    // treehouses running inside it, so they're drawn clustered around it.
+    // Nested treehouses are marked so they can be drawn smaller.
+    function landProcesses(land) {
+        const trees = land.trees || [];
+        const treeIds = new Set(trees.map(function (t) { return t.id; }));
+        const children = new Map();
+        const orphans = [];
+        (land.treehouses || []).forEach(function (th) {
+            if (th.parent_id && treeIds.has(th.parent_id)) {
+                if (!children.has(th.parent_id)) children.set(th.parent_id, []);
+                children.get(th.parent_id).push(Object.assign({ nested: true }, th));
+            } else {
+                orphans.push(th);
+            }
+        });
+
+        const result = [];
+        trees.forEach(function (tree) {
+            result.push(tree);
+            (children.get(tree.id) || []).forEach(function (th) { result.push(th); });
+        });
+        return result.concat(orphans, land.nims || []);
+    }
+
+    function drawProcesses(processes, x, y, w) {
+        const size = 9;
+        const perRow = Math.max(1, Math.floor((w - 16) / (size * 2 + 6)));
+        processes.forEach(function (proc, i) {
+            const radius = proc.nested ? size - 3 : size;
+            const cx = x + 8 + size + (i % perRow) * (size * 2 + 6);
+            const cy = y + size + Math.floor(i / perRow) * (size * 2 + 6);
+            const color = proc.state === "failed" ? COLORS.failed : COLORS[proc.type] || COLORS.text;
+
+            if (proc.id === MORE_PROCESSES_ID) {
+                // Stands for the processes folded by the server's process limit
+                ctx.fillStyle = COLORS.muted;
+                ctx.font = "bold 10px system-ui, sans-serif";
+                ctx.textAlign = "center";
+                ctx.textBaseline = "middle";
+                ctx.fillText(proc.name, cx, cy, size * 2 + 4);
+                ctx.textAlign = "start";
+                ctx.textBaseline = "alphabetic";
+                hitboxes.push({
+                    x: cx - radius, y: cy - radius, w: radius * 2, h: radius * 2,
+                    text: proc.name + " processes\nram: " + formatBytes(proc.ram_allocated),
+                });
+                return;
+            }
+
+            ctx.beginPath();
+            ctx.arc(cx, cy, radius, 0, Math.PI * 2);
+            ctx.fillStyle = "rgba(0, 0, 0, 0.35)";
+            ctx.fill();
+
+            const progress = Math.max(0, Math.min(1, proc.progress || 0));
+            ctx.beginPath();
+            ctx.moveTo(cx, cy);
+            ctx.arc(cx, cy, radius, -Math.PI / 2, -Math.PI / 2 + progress * Math.PI * 2);
+            ctx.closePath();
+            ctx.fillStyle = color;
+            ctx.fill();
+
+            ctx.beginPath();
+            ctx.arc(cx, cy, radius, 0, Math.PI * 2);
+            ctx.strokeStyle = color;
+            ctx.lineWidth = 1.5;
+            ctx.stroke();
+
+            hitboxes.push({
+                x: cx - radius, y: cy - radius, w: radius * 2, h: radius * 2,
+                text: proc.type + ": " + (proc.name || proc.id) +
+                    (proc.state ? "\nstate: " + proc.state : "") +
+                    (proc.parent_id ? "\nparent: " + proc.parent_id : "") +
+                    "\nprogress: " + Math.round(progress * 100) + "%" +
+                    "\nram: " + formatBytes(proc.ram_allocated),
+            });
+        });
+    }
+
+    function drawLand(land) {
+        const x = PADDING + land.grid_x * (TILE + GAP);
+        const y = PADDING + land.grid_y * (TILE + GAP);
+
+        const status = landStatus(land);
+        ctx.fillStyle = land.is_manaland ? COLORS.mana : COLORS.land;
+        ctx.fillRect(x, y, TILE, TILE);
+        if (status === "ok") {
+            ctx.strokeStyle = COLORS.border;
+            ctx.lineWidth = 1;
+            ctx.strokeRect(x + 0.5, y + 0.5, TILE - 1, TILE - 1);
+        } else {
+            // Highlight lands that are nearly full
+            ctx.strokeStyle = statusColor(status);
+            ctx.lineWidth = 3;
+            ctx.strokeRect(x + 1.5, y + 1.5, TILE - 3, TILE - 3);
+        }
+
+        ctx.fillStyle = COLORS.text;
+        ctx.font = "bold 12px system-ui, sans-serif";
+        ctx.fillText(land.hostname || land.id, x + 8, y + 18, TILE - 16);
+
+        ctx.fillStyle = COLORS.muted;
+        ctx.font = "11px system-ui, sans-serif";
+        ctx.fillText(formatBytes(land.ram_allocated) + " / " + formatBytes(land.ram_total), x + 8, y + 34, TILE - 16);
+
+        // Occupancy bar along the bottom edge
+        const occupancy = Math.max(0, Math.min(1, land.occupancy || 0));
+        ctx.fillStyle = "rgba(0, 0, 0, 0.4)";
+        ctx.fillRect(x + 8, y + TILE - 16, TILE - 16, 8);
+        ctx.fillStyle = statusColor(status);
+        ctx.fillRect(x + 8, y + TILE - 16, (TILE - 16) * occupancy, 8);
+
+        const processes = landProcesses(land);
+        drawProcesses(processes, x, y + 46, TILE);
+
+        hitboxes.push({
+            x: x, y: y, w: TILE, h: TILE,
+            text: (land.is_manaland ? "manaland " : "land ") + (land.hostname || land.id) +
+                "\noccupancy: " + Math.round(occupancy * 100) + "% (" + status + ")" +
+                "\nram: " + formatBytes(land.ram_allocated) + " / " + formatBytes(land.ram_total) +
+                "\nprocesses: " + (land.process_count != null ? land.process_count : processes.length),
+        });
+    }
+
+    // drawEdges draws the message flow between lands that share a subject.
+    // Edges within one land aren't drawn.
+    function drawEdges(lands, edges) {
+        const centers = {};
+        lands.forEach(function (land) {
+            centers[land.id] = {
+                x: PADDING + land.grid_x * (TILE + GAP) + TILE / 2,
+                y: PADDING + land.grid_y * (TILE + GAP) + TILE / 2,
+            };
+        });
+
+        const seen = {};
+        ctx.strokeStyle = COLORS.edge;
+        ctx.lineWidth = 2;
+        edges.forEach(function (edge) {
+            const from = centers[edge.from_land];
+            const to = centers[edge.to_land];
+            const key = [edge.from_land, edge.to_land].sort().join("\n");
+            if (!from || !to || edge.from_land === edge.to_land || seen[key]) return;
+            seen[key] = true;
+            ctx.beginPath();
+            ctx.moveTo(from.x, from.y);
+            ctx.lineTo(to.x, to.y);
+            ctx.stroke();
+        });
+    }
+
+    function draw() {
+        const width = canvas.clientWidth;
+        const height = canvas.clientHeight;
+        ctx.clearRect(0, 0, width, height);
+        hitboxes = [];
+
+        (world.lands || []).forEach(drawLand);
+        drawEdges(world.lands || [], world.edges || []);
+
+        if (!world.lands || world.lands.length === 0) {
+            ctx.fillStyle = COLORS.muted;
+            ctx.font = "14px system-ui, sans-serif";
+            ctx.fillText("No lands reported yet", PADDING, PADDING + 14);
+        }
+    }
+
+    function renderSummary(summary) {
+        const items = [
+            ["Lands", summary.land_count || 0],
+            ["Manalands", summary.manaland_count || 0],
+            ["Trees", summary.tree_count || 0],
+            ["Treehouses", summary.treehouse_count || 0],
+            ["Nims", summary.nim_count || 0],
+            ["RAM", (summary.ram_allocated_human || formatBytes(summary.ram_allocated)) + " / " +
+                (summary.total_ram_human || formatBytes(summary.total_ram))],
+            ["Occupancy", Math.round((summary.occupancy || 0) * 100) + "%"],
+        ];
+        summaryEl.innerHTML = "";
+        items.forEach(function (item) {
+            const span = document.createElement("span");
+            span.textContent = item[0] + ": ";
+            const value = document.createElement("b");
+            value.textContent = item[1];
+            span.appendChild(value);
+            summaryEl.appendChild(span);
+        });
+    }
+
+    // renderAge shows how long ago the server last received state, flagged as
+    // stale once it exceeds the server's stale_after threshold.
+    function renderAge() {
+        const summary = world.summary || {};
+        if (!summary.last_update) {
+            statusEl.textContent = "No updates received yet";
+            statusEl.classList.remove("stale");
+            return;
+        }
+        const age = Math.max(0, Math.round((Date.now() - Date.parse(summary.last_update)) / 1000));
+        const stale = summary.stale_after > 0 && age > summary.stale_after;
+        statusEl.textContent = "Last update " + new Date(summary.last_update).toLocaleTimeString() +
+            " (" + age + "s ago" + (stale ? ", stale" : "") + ")";
+        statusEl.classList.toggle("stale", stale);
+    }
+
+    function poll() {
+        fetch("api/viewmodel", { cache: "no-store" })
+            .then(function (resp) {
+                if (!resp.ok) throw new Error("HTTP " + resp.status);
+                return resp.json();
+            })
+            .then(function (data) {
+                world = data;
+                renderSummary(data.summary || {});
+                live = data.version === SCHEMA_VERSION;
+                if (live) {
+                    renderAge();
+                } else {
+                    statusEl.classList.remove("stale");
+                    statusEl.textContent = "Unsupported payload version " + data.version + ", expected " + SCHEMA_VERSION;
+                }
+                draw();
+            })
+            .catch(function (err) {
+                live = false;
+                statusEl.classList.remove("stale");
+                statusEl.textContent = "Disconnected: " + err.message;
+            })
+            .finally(function () {
+                setTimeout(poll, POLL_INTERVAL_MS);
+            });
+    }
+
+    canvas.addEventListener("mousemove", function (ev) {
+        const rect = canvas.getBoundingClientRect();
+        const mx = ev.clientX - rect.left;
+        const my = ev.clientY - rect.top;
+
+        // Processes are pushed after their land, so search from the end.
+        for (let i = hitboxes.length - 1; i >= 0; i--) {
+            const h = hitboxes[i];
+            if (mx >= h.x && mx <= h.x + h.w && my >= h.y && my <= h.y + h.h) {
+                tooltip.textContent = h.text;
+                tooltip.style.left = (mx + 14) + "px";
+                tooltip.style.top = (my + 14) + "px";
+                tooltip.hidden = false;
+                return;
+            }
+        }
+        tooltip.hidden = true;
+    });
+
+    canvas.addEventListener("mouseleave", function () {
+        tooltip.hidden = true;
+    });
+
+    window.addEventListener("resize", resize);
+    resize();
+    poll();
+    setInterval(function () {
+        if (live) renderAge();
+    }, 1000);
+})();
@@ -0,0 +1,152 @@
+package nimsforestviewer
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// TargetAdmin is the target list the WebTarget admin endpoints change at runtime.
+// *Viewer implements it.
+type TargetAdmin interface {
+	Targets() []Target
+	AddTarget(t Target) error
+	RemoveTargetByName(name string) Target
+}
+
+// TargetFactory creates a target from the JSON body of POST /admin/targets,
+// e.g. {"type": "smarttv", "ip": "192.168.1.20"}. The format is up to the factory.
+type TargetFactory func(ctx context.Context, spec json.RawMessage) (Target, error)
+
+// maxAdminBody bounds the size of a POST /admin/targets body.
+const maxAdminBody = 64 * 1024
+
+// WithAdmin enables the admin endpoints, which change admin's targets at runtime:
+//
+//	GET    /admin/targets          lists targets with their health
+//	POST   /admin/targets          creates a target with factory and adds it
+//	DELETE /admin/targets/{name}   removes and closes the target with that name
+//
+// The WebTarget serving the endpoints can't remove itself; DELETE returns 409 for it.
+// Every request must carry "Authorization: Bearer <token>"; an empty token leaves
+// the endpoints disabled. POST returns 501 when factory is nil.
+//
+// Anyone holding the token can take displays offline or make the server connect
+// to arbitrary hosts through the factory, and WebTarget serves plain HTTP, so the
+// token is visible on the network. Serve it only on a trusted network or behind
+// a TLS-terminating proxy, and validate specs in the factory. The admin endpoints
+// never send CORS headers, so browsers on other origins can't call them.
+func WithAdmin(admin TargetAdmin, token string, factory TargetFactory) WebOption {
+	return func(t *WebTarget) {
+		t.admin = admin
+		t.adminToken = token
+		t.adminFactory = factory
+	}
+}
+
+// adminTargetJSON describes a target in GET /admin/targets.
+type adminTargetJSON struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+}
+
+// registerAdmin adds the admin endpoints to mux if they are enabled.
+func (t *WebTarget) registerAdmin(mux *http.ServeMux) {
+	if t.admin == nil || t.adminToken == "" {
+		return
+	}
+	mux.HandleFunc("GET /admin/targets", t.requireToken(t.handleAdminList))
+	mux.HandleFunc("POST /admin/targets", t.requireToken(t.handleAdminAdd))
+	mux.HandleFunc("DELETE /admin/targets/{name}", t.requireToken(t.handleAdminRemove))
+}
+
+// requireToken rejects requests without the admin bearer token.
+func (t *WebTarget) requireToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(t.adminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (t *WebTarget) handleAdminList(w http.ResponseWriter, r *http.Request) {
+	targets := t.admin.Targets()
+	list := make([]adminTargetJSON, len(targets))
+	for i, target := range targets {
+		list[i] = adminTargetJSON{Name: target.Name(), Healthy: true}
+		if checker, ok := target.(HealthChecker); ok {
+			if err := checker.Health(); err != nil {
+				list[i].Healthy = false
+				list[i].Error = err.Error()
+			}
+		}
+	}
+	t.writeJSON(w, r, http.StatusOK, list)
+}
+
+func (t *WebTarget) handleAdminAdd(w http.ResponseWriter, r *http.Request) {
+	if t.adminFactory == nil {
+		http.Error(w, "adding targets is not supported", http.StatusNotImplemented)
+		return
+	}
+	spec, err := io.ReadAll(io.LimitReader(r.Body, maxAdminBody+1))
+	if err != nil {
+		http.Error(w, "read body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(spec) > maxAdminBody {
+		http.Error(w, "body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if !json.Valid(spec) {
+		http.Error(w, "body is not valid JSON", http.StatusBadRequest)
+		return
+	}
+
+	target, err := t.adminFactory(r.Context(), spec)
+	if err != nil {
+		http.Error(w, "create target: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := t.admin.AddTarget(target); err != nil {
+		target.Close()
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	t.log().Info("target added via admin API", "target", target.Name())
+
+	t.writeJSON(w, r, http.StatusCreated, adminTargetJSON{Name: target.Name(), Healthy: true})
+}
+
+func (t *WebTarget) handleAdminRemove(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	// Closing this target would wait for this very request to finish
+	for _, target := range t.admin.Targets() {
+		if target.Name() != name {
+			continue
+		}
+		if target == Target(t) {
+			http.Error(w, "cannot remove the target serving the admin API", http.StatusConflict)
+			return
+		}
+		break
+	}
+	target := t.admin.RemoveTargetByName(name)
+	if target == nil {
+		http.NotFound(w, r)
+		return
+	}
+	t.log().Info("target removed via admin API", "target", name)
+	if err := target.Close(); err != nil {
+		t.log().Warn("close removed target", "target", name, "error", err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
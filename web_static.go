@@ -0,0 +1,98 @@
+package nimsforestviewer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"html"
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// staticContentTypes fixes the Content-Type of common asset types, which
+// mime.TypeByExtension otherwise takes from the host's possibly incomplete tables.
+var staticContentTypes = map[string]string{
+	".html":  "text/html; charset=utf-8",
+	".js":    "text/javascript; charset=utf-8",
+	".mjs":   "text/javascript; charset=utf-8",
+	".css":   "text/css; charset=utf-8",
+	".json":  "application/json",
+	".map":   "application/json",
+	".svg":   "image/svg+xml",
+	".png":   "image/png",
+	".jpg":   "image/jpeg",
+	".ico":   "image/x-icon",
+	".webp":  "image/webp",
+	".woff2": "font/woff2",
+	".wasm":  "application/wasm",
+}
+
+// fingerprinted matches asset names with a content hash, such as "app.3f9a1c2b.js".
+var fingerprinted = regexp.MustCompile(`\.[0-9a-f]{8,}\.[a-z0-9]+$`)
+
+// headTag matches the opening tag of an HTML document's head.
+var headTag = regexp.MustCompile(`(?i)<head(\s[^>]*)?>`)
+
+// serveIndexWithBase serves the root index.html from assets with a <base href>
+// of base inserted into its head, so its relative links resolve under a path
+// prefix. Other requests, and index files that already set a base, go to next.
+func serveIndexWithBase(next http.Handler, assets fs.FS, base string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		data, err := fs.ReadFile(assets, "index.html")
+		loc := headTag.FindIndex(data)
+		if err != nil || loc == nil || bytes.Contains(bytes.ToLower(data), []byte("<base")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		tag := `<base href="` + html.EscapeString(base) + `">`
+		page := make([]byte, 0, len(data)+len(tag))
+		page = append(page, data[:loc[1]]...)
+		page = append(page, tag...)
+		page = append(page, data[loc[1]:]...)
+
+		sum := sha256.Sum256(page)
+		h := w.Header()
+		h.Set("Cache-Control", "no-cache")
+		h.Set("Content-Type", staticContentTypes[".html"])
+		h.Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+		http.ServeContent(w, r, "index.html", time.Time{}, bytes.NewReader(page))
+	})
+}
+
+// staticCacheHeaders sets Cache-Control and Content-Type on static assets.
+// Fingerprinted assets never change under the same name, so browsers may keep
+// them for a year; everything else, including index.html, must be revalidated,
+// which is cheap with etags. etags, keyed by asset path, is used for assets
+// without modification times, such as the embedded frontend; nil leaves
+// validation to the file server.
+func staticCacheHeaders(next http.Handler, etags map[string]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+		if name == "" || strings.HasSuffix(r.URL.Path, "/") {
+			name = path.Join(name, "index.html")
+		}
+
+		h := w.Header()
+		if fingerprinted.MatchString(name) {
+			h.Set("Cache-Control", "public, max-age=31536000, immutable")
+		} else {
+			h.Set("Cache-Control", "no-cache")
+		}
+		if ct, ok := staticContentTypes[path.Ext(name)]; ok {
+			h.Set("Content-Type", ct)
+		}
+		if etag, ok := etags[name]; ok {
+			h.Set("ETag", etag) // http.FileServer answers If-None-Match with it
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,93 @@
+package nimsforestviewer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// summaryKeepalive is how often an idle summary stream sends a comment, so
+// proxies don't time it out and a vanished client is noticed.
+const summaryKeepalive = 15 * time.Second
+
+// handleSummaryStream streams the summary as server-sent events: the current one
+// on connect, then one event whenever an update changes it. Each event is a
+// "summary" event whose data is a SummaryJSON without last_update; an open
+// stream is itself the sign the data is live.
+func (t *WebTarget) handleSummaryStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	ch := make(chan SummaryJSON, 1)
+	t.mu.Lock()
+	t.summarySubs[ch] = struct{}{}
+	if t.state != nil {
+		ch <- summaryToJSON(t.state.Summary)
+	}
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.summarySubs, ch)
+		t.mu.Unlock()
+	}()
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no") // Stop nginx from buffering the stream
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(summaryKeepalive)
+	defer keepalive.Stop()
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case <-t.closing:
+			return
+		case summary := <-ch:
+			err = writeSummaryEvent(w, summary)
+		case <-keepalive.C:
+			_, err = fmt.Fprint(w, ": keepalive\n\n")
+		}
+		if err != nil {
+			// The client went away; the deferred unsubscribe cleans up
+			t.log().Debug("summary stream closed", "remote", r.RemoteAddr, "error", err)
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// writeSummaryEvent writes summary as one server-sent event.
+func writeSummaryEvent(w http.ResponseWriter, summary SummaryJSON) error {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("encode summary: %w", err)
+	}
+	_, err = fmt.Fprintf(w, "event: summary\ndata: %s\n\n", data)
+	return err
+}
+
+// publishSummary sends state's summary to every stream. A stream that hasn't
+// taken the previous summary yet gets it replaced, so slow clients skip
+// intermediate values instead of blocking updates. Callers must hold t.mu.
+func (t *WebTarget) publishSummary(state *ViewState) {
+	var summary SummaryJSON
+	if state != nil {
+		summary = summaryToJSON(state.Summary)
+	}
+	for ch := range t.summarySubs {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- summary
+	}
+}